@@ -0,0 +1,27 @@
+// Package cache stores the generated CRD manifests by the digest
+// of the generation input, so repeated generations are instantaneous.
+package cache
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Cache stores the manifests by content digest; implementations
+// must be safe for concurrent use.
+type Cache interface {
+	// Get returns the manifest stored under the key, if any.
+	Get(key string) ([]byte, bool)
+	// Put stores the manifest under the key.
+	Put(key string, manifest []byte) error
+}
+
+var keyRE = regexp.MustCompile(`^[0-9a-f]{8,128}$`)
+
+// validateKey rejects the keys that are not lowercase hex digests.
+func validateKey(key string) error {
+	if !keyRE.MatchString(key) {
+		return fmt.Errorf("invalid cache key '%s': must be a lowercase hex digest", key)
+	}
+	return nil
+}
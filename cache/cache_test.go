@@ -0,0 +1,100 @@
+package cache_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen/cache"
+)
+
+func key(i int) string {
+	return fmt.Sprintf("%064x", i)
+}
+
+func TestDisk(t *testing.T) {
+	dir := t.TempDir()
+
+	c, err := cache.NewDisk(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCache(t, c)
+
+	if err := c.Put("../../etc/passwd", []byte("x")); err == nil {
+		t.Errorf("expected an error for an invalid key")
+	}
+
+	// stored manifests survive the cache instance
+	other, err := cache.NewDisk(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := other.Get(key(2)); !ok {
+		t.Errorf("expected a hit on the same directory")
+	}
+}
+
+func TestLRU(t *testing.T) {
+	c := cache.NewLRU(2)
+
+	testCache(t, c)
+
+	c.Put(key(1), []byte("one"))
+	c.Put(key(2), []byte("two"))
+	c.Get(key(1))
+	c.Put(key(3), []byte("three"))
+
+	if c.Len() != 2 {
+		t.Fatalf("expected 2 manifests, got %d", c.Len())
+	}
+
+	if _, ok := c.Get(key(2)); ok {
+		t.Errorf("expected the least recently used manifest to be evicted")
+	}
+
+	for _, i := range []int{1, 3} {
+		if _, ok := c.Get(key(i)); !ok {
+			t.Errorf("expected manifest %d to be cached", i)
+		}
+	}
+}
+
+func testCache(t *testing.T, c cache.Cache) {
+	t.Helper()
+
+	if _, ok := c.Get(key(1)); ok {
+		t.Fatalf("expected a miss on an empty cache")
+	}
+
+	if err := c.Put(key(1), []byte("---\nkind: CustomResourceDefinition\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok := c.Get(key(1))
+	if !ok {
+		t.Fatalf("expected a hit")
+	}
+	if !strings.Contains(string(got), "CustomResourceDefinition") {
+		t.Errorf("unexpected manifest: %s", got)
+	}
+
+	// callers must not be able to alter the stored manifest
+	got[0] = 'X'
+	if again, _ := c.Get(key(1)); again[0] != '-' {
+		t.Errorf("stored manifest altered by the caller")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c.Put(key(i%4), []byte(fmt.Sprintf("manifest %d", i%4)))
+			c.Get(key(i % 4))
+		}(i)
+	}
+	wg.Wait()
+}
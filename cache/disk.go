@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+var _ Cache = (*Disk)(nil)
+
+// Disk is a Cache storing every manifest in a file named
+// after its key, e.g. '<dir>/ab/abcdef...yaml'.
+type Disk struct {
+	dir string
+}
+
+// NewDisk returns a Disk cache rooted at dir, creating it if needed.
+func NewDisk(dir string) (*Disk, error) {
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+	}
+
+	return &Disk{dir: dir}, nil
+}
+
+func (c *Disk) Get(key string) ([]byte, bool) {
+	if validateKey(key) != nil {
+		return nil, false
+	}
+
+	dat, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+
+	return dat, true
+}
+
+func (c *Disk) Put(key string, manifest []byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	dst := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+
+	// write then rename, so that readers never see partial manifests
+	fp, err := os.CreateTemp(filepath.Dir(dst), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(fp.Name())
+
+	if _, err := fp.Write(manifest); err != nil {
+		fp.Close()
+		return err
+	}
+
+	if err := fp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(fp.Name(), dst)
+}
+
+func (c *Disk) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".yaml")
+}
@@ -0,0 +1,78 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"sync"
+)
+
+var _ Cache = (*LRU)(nil)
+
+// LRU is an in-memory Cache holding at most size manifests,
+// evicting the least recently used first.
+type LRU struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+type entry struct {
+	key      string
+	manifest []byte
+}
+
+// NewLRU returns an LRU cache holding at most size manifests;
+// a size lower than 1 is treated as 1.
+func NewLRU(size int) *LRU {
+	return &LRU{
+		size:  max(size, 1),
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+func (c *LRU) Get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	return bytes.Clone(el.Value.(*entry).manifest), true
+}
+
+func (c *LRU) Put(key string, manifest []byte) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		el.Value.(*entry).manifest = bytes.Clone(manifest)
+		c.ll.MoveToFront(el)
+		return nil
+	}
+
+	c.items[key] = c.ll.PushFront(&entry{key: key, manifest: bytes.Clone(manifest)})
+
+	for c.ll.Len() > c.size {
+		el := c.ll.Back()
+		c.ll.Remove(el)
+		delete(c.items, el.Value.(*entry).key)
+	}
+
+	return nil
+}
+
+// Len returns the number of cached manifests.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
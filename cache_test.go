@@ -0,0 +1,60 @@
+package crdgen_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/cache"
+	"github.com/krateoplatformops/crdgen/getter"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCache(t *testing.T) {
+	opts := crdgen.Options{
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Xapp",
+		},
+		Native:               true,
+		Cache:                cache.NewLRU(8),
+		SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+	}
+
+	first := crdgen.Generate(context.Background(), opts)
+	if first.Err != nil {
+		t.Fatal(first.Err)
+	}
+	if first.CacheHit {
+		t.Fatalf("expected a cache miss on the first generation")
+	}
+
+	// same schema, different layout
+	opts.SpecJsonSchemaGetter = getter.Bytes("{\n  \"type\":  \"object\"\n}")
+
+	second := crdgen.Generate(context.Background(), opts)
+	if second.Err != nil {
+		t.Fatal(second.Err)
+	}
+	if !second.CacheHit {
+		t.Fatalf("expected a cache hit on the second generation")
+	}
+	if !bytes.Equal(first.Manifest, second.Manifest) {
+		t.Errorf("expected the cached manifest")
+	}
+	if len(second.CRDs) != 1 {
+		t.Errorf("expected the decoded CRD of the cached manifest, got %d", len(second.CRDs))
+	}
+
+	opts.Managed = true
+
+	third := crdgen.Generate(context.Background(), opts)
+	if third.Err != nil {
+		t.Fatal(third.Err)
+	}
+	if third.CacheHit {
+		t.Fatalf("expected a cache miss after changing the options")
+	}
+}
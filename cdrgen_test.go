@@ -49,8 +49,13 @@ type urlJsonSchemaGetter struct {
 	uri string
 }
 
-func (f *urlJsonSchemaGetter) Get() ([]byte, error) {
-	res, err := http.Get(f.uri)
+func (f *urlJsonSchemaGetter) Get(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.uri, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,169 @@
+package crdgen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/krateoplatformops/crdgen/internal/chart"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// ChartGVK is the GVK, and the resource names, proposed for the CRD
+// generated from a Helm chart.
+type ChartGVK struct {
+	GVK schema.GroupVersionKind
+	// Plural, Singular and ListKind are the resource names Generate
+	// derives from the kind, e.g. "fireworksapps", "fireworksapp" and
+	// "FireworksAppList".
+	Plural   string
+	Singular string
+	ListKind string
+	// Normalizations describes how every part of
+	// the GVK was derived from the chart metadata.
+	Normalizations []Normalization
+}
+
+// Normalization describes how a chart metadata value
+// was turned into a part of the GVK.
+type Normalization struct {
+	// Part is "group", "version" or "kind".
+	Part string
+	// From is the original value, e.g. the chart version "1.2.3".
+	From string
+	// To is the normalized value, e.g. "v1-2-3".
+	To string
+	// Reason explains the change.
+	Reason string
+}
+
+func (n Normalization) String() string {
+	return fmt.Sprintf("%s: '%s' => '%s' (%s)", n.Part, n.From, n.To, n.Reason)
+}
+
+// GVKFromChart reads the Chart.yaml of the chart (directory or .tgz
+// archive) and proposes the GVK of its CRD: the kind is the CamelCased
+// chart name (e.g. "fireworks-app" => "FireworksApp"), the version is
+// derived from the chart semver (e.g. "1.2.3" => "v1-2-3") and the group
+// is the lowercase chart name followed by groupSuffix (e.g. "krateo.io"
+// => "fireworks-app.krateo.io"). The resource names are the ones
+// derived from the kind when not set in the Options.
+func GVKFromChart(name, groupSuffix string) (*ChartGVK, error) {
+	c, err := chart.Load(name)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &ChartGVK{}
+
+	res.GVK.Kind, err = chartKind(c.Metadata.Name, res)
+	if err != nil {
+		return nil, err
+	}
+
+	res.GVK.Version, err = chartVersion(c.Metadata.Version, res)
+	if err != nil {
+		return nil, err
+	}
+
+	res.GVK.Group, err = chartGroup(c.Metadata.Name, groupSuffix, res)
+	if err != nil {
+		return nil, err
+	}
+
+	nms, err := resolveNames(Kind{GVK: res.GVK})
+	if err != nil {
+		return nil, err
+	}
+	res.Plural, res.Singular, res.ListKind = nms.plural, nms.singular, nms.listKind
+
+	return res, nil
+}
+
+func (c *ChartGVK) note(part, from, to, reason string) {
+	c.Normalizations = append(c.Normalizations, Normalization{
+		Part: part, From: from, To: to, Reason: reason,
+	})
+}
+
+var nonAlphanumericRE = regexp.MustCompile(`[^a-zA-Z0-9]+`)
+
+func chartKind(name string, res *ChartGVK) (string, error) {
+	words := nonAlphanumericRE.Split(name, -1)
+
+	kind := strings.Builder{}
+	for _, el := range words {
+		if len(el) == 0 {
+			continue
+		}
+		rs := []rune(el)
+		rs[0] = unicode.ToUpper(rs[0])
+		kind.WriteString(string(rs))
+	}
+
+	val := kind.String()
+	if len(val) == 0 || !unicode.IsLetter([]rune(val)[0]) {
+		return "", fmt.Errorf("invalid kind '%s' from chart name '%s': must start with a letter", val, name)
+	}
+
+	if err := validateLabel("kind", strings.ToLower(val)); err != nil {
+		return "", err
+	}
+
+	res.note("kind", name, val, "CamelCased chart name")
+	return val, nil
+}
+
+var semverRE = regexp.MustCompile(`^v?(\d+)\.(\d+)\.(\d+)(?:-([0-9A-Za-z.-]+))?(?:\+([0-9A-Za-z.-]+))?$`)
+
+func chartVersion(version string, res *ChartGVK) (string, error) {
+	m := semverRE.FindStringSubmatch(version)
+	if m == nil {
+		return "", fmt.Errorf("invalid chart version '%s': must be a semantic version, e.g. '1.2.3'", version)
+	}
+
+	val := fmt.Sprintf("v%s-%s-%s", m[1], m[2], m[3])
+	reason := "dots replaced with dashes, 'v' prefixed"
+	if len(m[4]) > 0 {
+		pre := strings.Trim(nonAlphanumericRE.ReplaceAllString(strings.ToLower(m[4]), "-"), "-")
+		val = fmt.Sprintf("%s-%s", val, pre)
+		reason = fmt.Sprintf("%s, pre-release '%s' lowercased with dashes", reason, m[4])
+	}
+	if len(m[5]) > 0 {
+		reason = fmt.Sprintf("%s, build metadata '%s' dropped", reason, m[5])
+	}
+
+	if errs := validation.IsDNS1035Label(val); len(errs) > 0 {
+		return "", fmt.Errorf("invalid version '%s' from chart version '%s': %s",
+			val, version, strings.Join(errs, ", "))
+	}
+
+	res.note("version", version, val, reason)
+	return val, nil
+}
+
+func chartGroup(name, suffix string, res *ChartGVK) (string, error) {
+	suffix = strings.Trim(suffix, ".")
+	if len(suffix) == 0 {
+		return "", fmt.Errorf("missing group suffix")
+	}
+
+	label := strings.Trim(nonAlphanumericRE.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	val := fmt.Sprintf("%s.%s", label, suffix)
+
+	errs := append(validation.IsDNS1123Label(label), validation.IsDNS1123Subdomain(val)...)
+	if len(errs) > 0 {
+		return "", fmt.Errorf("invalid group '%s' from chart name '%s': %s",
+			val, name, strings.Join(errs, ", "))
+	}
+
+	reason := fmt.Sprintf("chart name followed by '%s'", suffix)
+	if label != name {
+		reason = fmt.Sprintf("lowercased chart name, with dashes, followed by '%s'", suffix)
+	}
+
+	res.note("group", name, val, reason)
+	return val, nil
+}
@@ -0,0 +1,94 @@
+package crdgen_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestGVKFromChart(t *testing.T) {
+	tests := []struct {
+		name     string
+		version  string
+		suffix   string
+		expected schema.GroupVersionKind
+		plural   string
+		listKind string
+	}{
+		{
+			name: "fireworks-app", version: "1.2.3", suffix: "krateo.io",
+			expected: schema.GroupVersionKind{Group: "fireworks-app.krateo.io", Version: "v1-2-3", Kind: "FireworksApp"},
+			plural:   "fireworksapps", listKind: "FireworksAppList",
+		},
+		{
+			name: "my_Chart.v2", version: "v0.10.0-RC.1+build.7", suffix: ".composition.krateo.io.",
+			expected: schema.GroupVersionKind{Group: "my-chart-v2.composition.krateo.io", Version: "v0-10-0-rc-1", Kind: "MyChartV2"},
+			plural:   "mychartv2s", listKind: "MyChartV2List",
+		},
+		{
+			name: "postgresql", version: "12.0.0", suffix: "example.org",
+			expected: schema.GroupVersionKind{Group: "postgresql.example.org", Version: "v12-0-0", Kind: "Postgresql"},
+			plural:   "postgresqls", listKind: "PostgresqlList",
+		},
+	}
+
+	for _, tc := range tests {
+		dir := writeChart(t, tc.name, tc.version)
+
+		got, err := crdgen.GVKFromChart(dir, tc.suffix)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got.GVK != tc.expected {
+			t.Errorf("expected %v, got %v", tc.expected, got.GVK)
+		}
+
+		if got.Plural != tc.plural || got.ListKind != tc.listKind {
+			t.Errorf("expected names %s, %s, got %s, %s", tc.plural, tc.listKind, got.Plural, got.ListKind)
+		}
+		if want := strings.ToLower(tc.expected.Kind); got.Singular != want {
+			t.Errorf("expected singular %s, got %s", want, got.Singular)
+		}
+
+		if len(got.Normalizations) != 3 {
+			t.Errorf("expected 3 normalizations, got %v", got.Normalizations)
+		}
+	}
+
+	invalid := []struct {
+		name    string
+		version string
+		suffix  string
+	}{
+		{name: "app", version: "1.2", suffix: "krateo.io"},
+		{name: "app", version: "latest", suffix: "krateo.io"},
+		{name: "2048-game", version: "1.0.0", suffix: "krateo.io"},
+		{name: "app", version: "1.0.0", suffix: ""},
+		{name: "app", version: "1.0.0", suffix: "krateo_io"},
+	}
+
+	for _, tc := range invalid {
+		dir := writeChart(t, tc.name, tc.version)
+
+		if _, err := crdgen.GVKFromChart(dir, tc.suffix); err == nil {
+			t.Errorf("expected an error for chart '%s' version '%s' suffix '%s'", tc.name, tc.version, tc.suffix)
+		}
+	}
+}
+
+func writeChart(t *testing.T, name, version string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	dat := []byte("apiVersion: v2\nname: " + name + "\nversion: " + version + "\n")
+	if err := os.WriteFile(filepath.Join(dir, "Chart.yaml"), dat, 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
@@ -0,0 +1,447 @@
+// Command crdgen generates a CustomResourceDefinition from the
+// JSON schemas of the spec and of the status of a resource.
+//
+// Usage:
+//
+//	crdgen -spec spec.schema.json [-status status.schema.json] \
+//	  -group example.org -version v1alpha1 -kind Xapp [flags]
+//
+// The schemas may be JSON or YAML; '-' reads the schema from stdin.
+// The CRD is written as YAML (default) or JSON; the 'helm' and
+// 'kustomize' formats write a directory, or a tar stream to stdout.
+// With -sample the output is a sample custom resource instead, holding
+// the required fields only or all of them. With -check nothing is
+// written: the custom resources in the file are validated against the
+// generated CRD, as the API server would, and the violations reported.
+// With -provenance the CRD is annotated with the crdgen version, the
+// input digest, the schema files and the generation time, which
+// -reproducible omits.
+// The exit code is 0 on success, 2 for invalid flags, 3 for an invalid
+// JSON schema, a CRD or a custom resource the API server would reject,
+// 4 for a Go toolchain or controller-gen failure, 5 for an I/O failure
+// and 1 for any other failure.
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"github.com/krateoplatformops/crdgen/render"
+	"github.com/krateoplatformops/crdgen/sample"
+	"github.com/krateoplatformops/crdgen/validator"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// Exit codes.
+const (
+	exitOK = iota
+	exitFailure
+	exitUsage
+	exitSchema
+	exitToolchain
+	exitIO
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fset := flag.NewFlagSet("crdgen", flag.ContinueOnError)
+	fset.SetOutput(stderr)
+
+	var (
+		spec       = fset.String("spec", "", "spec JSON schema file (JSON or YAML), '-' for stdin")
+		status     = fset.String("status", "", "optional status JSON schema file (JSON or YAML), '-' for stdin")
+		group      = fset.String("group", "", "API group, e.g. 'example.org'")
+		version    = fset.String("version", "v1alpha1", "API version")
+		kind       = fset.String("kind", "", "resource kind, e.g. 'Xapp'")
+		categories = fset.String("categories", "", "comma separated categories")
+		scope      = fset.String("scope", string(apiextensionsv1.NamespaceScoped), "resource scope, Namespaced or Cluster")
+		managed    = fset.Bool("managed", false, "add the managed resource fields")
+		output     = fset.String("o", "", "output file, or directory for helm and kustomize (default stdout)")
+		format     = fset.String("format", "yaml", "output format: yaml, json, helm or kustomize")
+		lbls       = fset.String("labels", "", "comma separated key=value labels of the CRD")
+		annots     = fset.String("annotations", "", "comma separated key=value annotations of the CRD")
+		provenance = fset.Bool("provenance", false, "stamp the CRD with the provenance annotations")
+		reproduce  = fset.Bool("reproducible", false, "omit the generation time from the provenance annotations")
+		chartName  = fset.String("chart-name", "", "name of the Helm chart")
+		chartVer   = fset.String("chart-version", "", "version of the Helm chart")
+		sampleMode = fset.String("sample", "", "write a sample custom resource: required or all fields")
+		check      = fset.String("check", "", "validate the custom resources in the file (JSON or YAML), '-' for stdin")
+		native     = fset.Bool("native", false, "build the CRD in-process, without the Go toolchain")
+		genBinary  = fset.String("controller-gen", "", "path of a prebuilt controller-gen (default 'go run')")
+		maxDescLen = fset.Int("max-desc-len", -1, "truncate the schema descriptions longer than this, 0 drops them (default keep all)")
+		workdir    = fset.String("workdir", "crdgen", "name of the temporary Go module")
+		verbose    = fset.Bool("verbose", false, "log the generation steps to stderr")
+	)
+
+	if err := fset.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	if fset.NArg() > 0 {
+		return usage(stderr, "unexpected arguments: %s", strings.Join(fset.Args(), " "))
+	}
+	if len(*spec) == 0 {
+		return usage(stderr, "missing -spec")
+	}
+	if len(*group) == 0 || len(*version) == 0 || len(*kind) == 0 {
+		return usage(stderr, "-group, -version and -kind are required")
+	}
+	if countStdin(*spec, *status, *check) > 1 {
+		return usage(stderr, "only one of -spec, -status and -check can read from stdin")
+	}
+	if len(*check) > 0 && len(*sampleMode) > 0 {
+		return usage(stderr, "-check and -sample are mutually exclusive")
+	}
+
+	ropts := render.Options{
+		Chart: render.Chart{Name: *chartName, Version: *chartVer},
+	}
+	labels, err := splitPairs(*lbls)
+	if err != nil {
+		return usage(stderr, "invalid label %v", err)
+	}
+	annotations, err := splitPairs(*annots)
+	if err != nil {
+		return usage(stderr, "invalid annotation %v", err)
+	}
+	if *native && len(*genBinary) > 0 {
+		return usage(stderr, "-controller-gen and -native are mutually exclusive")
+	}
+	if *reproduce && !*provenance {
+		return usage(stderr, "-reproducible requires -provenance")
+	}
+
+	switch *format {
+	case "yaml", "json", "helm", "kustomize":
+	default:
+		return usage(stderr, "invalid format '%s'", *format)
+	}
+
+	switch *sampleMode {
+	case "":
+	case "required", "all":
+		if *format != "yaml" && *format != "json" {
+			return usage(stderr, "a sample can be written as yaml or json only")
+		}
+	default:
+		return usage(stderr, "invalid sample mode '%s': expected required or all", *sampleMode)
+	}
+
+	opts := crdgen.Options{
+		WorkDir: *workdir,
+		GVK: schema.GroupVersionKind{
+			Group:   *group,
+			Version: *version,
+			Kind:    *kind,
+		},
+		Categories:  splitList(*categories),
+		Scope:       apiextensionsv1.ResourceScope(*scope),
+		Managed:     *managed,
+		Native:      *native,
+		Verbose:     *verbose,
+		Labels:      labels,
+		Annotations: annotations,
+	}
+
+	// the CLI never exports the Go module, which alone needs the DeepCopy methods
+	opts.ControllerGen = crdgen.ControllerGen{
+		Binary:       *genBinary,
+		SkipDeepCopy: true,
+	}
+	if *maxDescLen >= 0 {
+		opts.ControllerGen.MaxDescLen = maxDescLen
+	}
+
+	if *provenance {
+		opts.Provenance = &crdgen.Provenance{
+			Source:        strings.Join(sources(*spec, *status), ","),
+			OmitTimestamp: *reproduce,
+		}
+	}
+
+	dat, code := readSchema("spec", *spec, stdin, stderr)
+	if code != exitOK {
+		return code
+	}
+	opts.SpecJsonSchemaGetter = getter.Bytes(dat)
+
+	if len(*status) > 0 {
+		dat, code := readSchema("status", *status, stdin, stderr)
+		if code != exitOK {
+			return code
+		}
+		opts.StatusJsonSchemaGetter = getter.Bytes(dat)
+	}
+
+	res := crdgen.Generate(ctx, opts)
+	if res.Err != nil {
+		fmt.Fprintf(stderr, "crdgen: %v\n", res.Err)
+		return exitCode(res.Err)
+	}
+
+	// the API server would reject the CRD
+	if len(res.Violations) > 0 {
+		for _, el := range res.Violations {
+			fmt.Fprintf(stderr, "crdgen: %s\n", el)
+		}
+		return exitSchema
+	}
+
+	if len(*check) > 0 {
+		return checkObjects(ctx, res, *check, stdin, stderr)
+	}
+
+	if len(*sampleMode) > 0 {
+		return writeSample(res, sample.Options{All: *sampleMode == "all"}, *format == "json", *output, stdout, stderr)
+	}
+
+	all := make([]*apiextensionsv1.CustomResourceDefinition, 0, len(res.CRDs))
+	for _, obj := range res.CRDs {
+		all = append(all, obj)
+	}
+
+	switch *format {
+	case "yaml", "json":
+		fn := render.YAML
+		if *format == "json" {
+			fn = render.JSON
+		}
+
+		dat, err := fn(all, ropts)
+		if err != nil {
+			fmt.Fprintf(stderr, "crdgen: %v\n", err)
+			return exitUsage
+		}
+
+		err = writeFile(*output, dat, stdout)
+		if err != nil {
+			fmt.Fprintf(stderr, "crdgen: writing CRD: %v\n", err)
+			return exitIO
+		}
+
+	default:
+		fn := render.HelmChart
+		if *format == "kustomize" {
+			fn = render.Kustomize
+		}
+
+		fsys, err := fn(all, ropts)
+		if err != nil {
+			fmt.Fprintf(stderr, "crdgen: %v\n", err)
+			return exitUsage
+		}
+
+		err = writeDir(*output, fsys, stdout)
+		if err != nil {
+			fmt.Fprintf(stderr, "crdgen: writing %s: %v\n", *format, err)
+			return exitIO
+		}
+	}
+
+	return exitOK
+}
+
+// checkObjects validates the custom resources in the named file
+// against the generated CRDs and reports the violations.
+func checkObjects(ctx context.Context, res crdgen.Result, name string, stdin io.Reader, stderr io.Writer) int {
+	dat, err := readInput(name, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "crdgen: reading custom resources: %v\n", err)
+		return exitIO
+	}
+
+	v, err := validator.ForResult(res)
+	if err != nil {
+		fmt.Fprintf(stderr, "crdgen: %v\n", err)
+		return exitFailure
+	}
+
+	all, err := v.Validate(ctx, dat)
+	if err != nil {
+		fmt.Fprintf(stderr, "crdgen: %s: %v\n", name, err)
+		return exitSchema
+	}
+
+	code := exitOK
+	for _, rep := range all {
+		for _, el := range rep.Pruned {
+			fmt.Fprintf(stderr, "crdgen: %s: %s: unknown field '%s'\n", name, rep, el)
+		}
+		for _, el := range rep.Violations {
+			fmt.Fprintf(stderr, "crdgen: %s: %s: %s\n", name, rep, el)
+			code = exitSchema
+		}
+	}
+
+	return code
+}
+
+// writeSample writes a sample custom resource of the generated CRD.
+func writeSample(res crdgen.Result, opts sample.Options, asJSON bool, output string, stdout, stderr io.Writer) int {
+	var obj *apiextensionsv1.CustomResourceDefinition
+	for _, el := range res.CRDs {
+		obj = el
+	}
+
+	fn := sample.YAML
+	if asJSON {
+		fn = sample.JSON
+	}
+
+	dat, err := fn(obj, opts)
+	if err != nil {
+		fmt.Fprintf(stderr, "crdgen: %v\n", err)
+		return exitFailure
+	}
+
+	if err := writeFile(output, dat, stdout); err != nil {
+		fmt.Fprintf(stderr, "crdgen: writing sample: %v\n", err)
+		return exitIO
+	}
+	return exitOK
+}
+
+// writeFile writes the data to the named file or to stdout when name is empty.
+func writeFile(name string, dat []byte, stdout io.Writer) error {
+	if len(name) == 0 {
+		_, err := stdout.Write(dat)
+		return err
+	}
+	return os.WriteFile(name, dat, 0o644)
+}
+
+// writeDir copies the files to the named directory or,
+// when name is empty, writes them to stdout as a tar stream.
+func writeDir(name string, fsys fs.FS, stdout io.Writer) error {
+	if len(name) > 0 {
+		return os.CopyFS(name, fsys)
+	}
+
+	tw := tar.NewWriter(stdout)
+	if err := tw.AddFS(fsys); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+// exitCode maps a generation error to the exit code.
+func exitCode(err error) int {
+	var pathErr *fs.PathError
+	switch {
+	case errors.Is(err, crdgen.ErrInvalidSchema):
+		return exitSchema
+	case errors.Is(err, crdgen.ErrToolchain):
+		return exitToolchain
+	case errors.As(err, &pathErr):
+		return exitIO
+	default:
+		return exitFailure
+	}
+}
+
+// readInput reads the named file or stdin when name is '-'.
+func readInput(name string, stdin io.Reader) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// readSchema reads the named JSON schema, converting the YAML
+// documents to JSON so that a malformed one is reported as an
+// invalid schema.
+func readSchema(what, name string, stdin io.Reader, stderr io.Writer) ([]byte, int) {
+	dat, err := readInput(name, stdin)
+	if err != nil {
+		fmt.Fprintf(stderr, "crdgen: reading %s JSON schema: %v\n", what, err)
+		return nil, exitIO
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(dat), []byte("{")) {
+		return dat, exitOK
+	}
+
+	res, err := yaml.YAMLToJSON(dat)
+	if err != nil {
+		fmt.Fprintf(stderr, "crdgen: parsing %s JSON schema: %v\n", what, err)
+		return nil, exitSchema
+	}
+	return res, exitOK
+}
+
+// sources returns the named schema files, skipping stdin.
+func sources(names ...string) []string {
+	var res []string
+	for _, el := range names {
+		if len(el) > 0 && el != "-" {
+			res = append(res, el)
+		}
+	}
+	return res
+}
+
+// splitPairs splits a comma separated list of key=value pairs.
+func splitPairs(s string) (map[string]string, error) {
+	var res map[string]string
+	for _, el := range splitList(s) {
+		k, v, ok := strings.Cut(el, "=")
+		if !ok {
+			return nil, fmt.Errorf("'%s': expected key=value", el)
+		}
+		if res == nil {
+			res = map[string]string{}
+		}
+		res[k] = v
+	}
+	return res, nil
+}
+
+// countStdin returns the number of inputs read from stdin.
+func countStdin(names ...string) int {
+	res := 0
+	for _, el := range names {
+		if el == "-" {
+			res++
+		}
+	}
+	return res
+}
+
+// splitList splits a comma separated list skipping the empty items.
+func splitList(s string) []string {
+	var res []string
+	for _, el := range strings.Split(s, ",") {
+		if el = strings.TrimSpace(el); len(el) > 0 {
+			res = append(res, el)
+		}
+	}
+	return res
+}
+
+func usage(w io.Writer, format string, args ...any) int {
+	fmt.Fprintf(w, "crdgen: "+format+"\n", args...)
+	fmt.Fprintln(w, "run 'crdgen -h' for usage")
+	return exitUsage
+}
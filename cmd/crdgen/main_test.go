@@ -0,0 +1,205 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	const spec = `
+type: object
+properties:
+  replicas:
+    type: integer
+`
+
+	dir := t.TempDir()
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"type": "object", "properties": {"name": {"type": "wrong"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	violating := filepath.Join(dir, "violating.json")
+	if err := os.WriteFile(violating, []byte(`{"type": "object", "properties": {"name": {"type": "string", "pattern": "[a-z"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	malformed := filepath.Join(dir, "malformed.yaml")
+	if err := os.WriteFile(malformed, []byte("type: object\nproperties:\n  name: [string\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	objects := filepath.Join(dir, "objects.yaml")
+	if err := os.WriteFile(objects, []byte("apiVersion: example.org/v1alpha1\nkind: Xapp\nmetadata:\n  name: demo\nspec:\n  replicas: 3\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	wrong := filepath.Join(dir, "wrong.yaml")
+	if err := os.WriteFile(wrong, []byte("apiVersion: example.org/v1alpha1\nkind: Xapp\nmetadata:\n  name: demo\nspec:\n  replicas: three\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string
+	}{
+		{
+			name: "stdin",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-categories", "krateo, apps"},
+			code: exitOK,
+			want: "name: xapps.example.org",
+		},
+		{
+			name: "json",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-format", "json", "-labels", "team=platform"},
+			code: exitOK,
+			want: `"team": "platform"`,
+		},
+		{
+			name: "provenance",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-annotations", "krateo.io/owner=platform", "-provenance", "-reproducible"},
+			code: exitOK,
+			want: "crdgen.krateo.io/digest:",
+		},
+		{
+			name: "reproducible without provenance",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-reproducible"},
+			code: exitUsage,
+		},
+		{
+			name: "invalid annotation",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-annotations", "owner"},
+			code: exitUsage,
+		},
+		{
+			name: "kustomize",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-format", "kustomize", "-o", filepath.Join(dir, "base")},
+			code: exitOK,
+		},
+		{
+			name: "invalid chart version",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-format", "helm", "-chart-version", "latest"},
+			code: exitUsage,
+		},
+		{
+			name: "sample",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-sample", "all"},
+			code: exitOK,
+			want: "replicas: 0",
+		},
+		{
+			name: "invalid sample mode",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-sample", "some"},
+			code: exitUsage,
+		},
+		{
+			name: "check",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-check", objects},
+			code: exitOK,
+		},
+		{
+			name: "check violations",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-check", wrong},
+			code: exitSchema,
+		},
+		{
+			name: "check and sample",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-check", objects, "-sample", "all"},
+			code: exitUsage,
+		},
+		{
+			name: "controller-gen and native",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-controller-gen", "/usr/local/bin/controller-gen"},
+			code: exitUsage,
+		},
+		{
+			name: "invalid format",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-format", "toml"},
+			code: exitUsage,
+		},
+		{
+			name: "missing kind",
+			args: []string{"-native", "-spec", "-", "-group", "example.org"},
+			code: exitUsage,
+		},
+		{
+			name: "invalid schema",
+			args: []string{"-native", "-spec", invalid, "-group", "example.org", "-kind", "Xapp"},
+			code: exitSchema,
+		},
+		{
+			name: "malformed yaml schema",
+			args: []string{"-native", "-spec", malformed, "-group", "example.org", "-kind", "Xapp"},
+			code: exitSchema,
+		},
+		{
+			name: "malformed yaml status schema",
+			args: []string{"-native", "-spec", "-", "-status", malformed, "-group", "example.org", "-kind", "Xapp"},
+			code: exitSchema,
+		},
+		{
+			name: "violations",
+			args: []string{"-native", "-spec", violating, "-group", "example.org", "-kind", "Xapp"},
+			code: exitSchema,
+		},
+		{
+			name: "missing file",
+			args: []string{"-native", "-spec", filepath.Join(dir, "missing.json"), "-group", "example.org", "-kind", "Xapp"},
+			code: exitIO,
+		},
+		{
+			name: "unwritable output",
+			args: []string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-o", filepath.Join(dir, "missing", "crd.yaml")},
+			code: exitIO,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := run(context.TODO(), tc.args, strings.NewReader(spec), &stdout, &stderr)
+			if code != tc.code {
+				t.Fatalf("expected exit code %d, got %d: %s", tc.code, code, stderr.String())
+			}
+
+			if !strings.Contains(stdout.String(), tc.want) {
+				t.Errorf("expected %q in output, got:\n%s", tc.want, stdout.String())
+			}
+		})
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "base", "kustomization.yaml")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRunLabelsDigest(t *testing.T) {
+	const spec = `{"type": "object"}`
+
+	digest := func(args ...string) string {
+		args = append([]string{"-native", "-spec", "-", "-group", "example.org", "-kind", "Xapp", "-provenance", "-reproducible"}, args...)
+
+		var stdout, stderr bytes.Buffer
+		if code := run(context.TODO(), args, strings.NewReader(spec), &stdout, &stderr); code != exitOK {
+			t.Fatalf("expected exit code %d, got %d: %s", exitOK, code, stderr.String())
+		}
+
+		for _, line := range strings.Split(stdout.String(), "\n") {
+			if strings.Contains(line, "crdgen.krateo.io/digest:") {
+				return line
+			}
+		}
+		t.Fatalf("missing digest annotation:\n%s", stdout.String())
+		return ""
+	}
+
+	if digest() == digest("-labels", "team=platform") {
+		t.Errorf("expected the labels to change the digest")
+	}
+}
@@ -0,0 +1,46 @@
+package crdgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// waitDelay bounds the time spent waiting for the output of a killed command.
+const waitDelay = 5 * time.Second
+
+// runCommand runs the command in the specified directory and environment
+// (nil means the current one) returning its combined output; the whole
+// process group is killed when ctx is done.
+func runCommand(ctx context.Context, dir string, env []string, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = env
+	cmd.WaitDelay = waitDelay
+	setProcessGroup(cmd)
+
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return out, ctx.Err()
+	}
+	return out, err
+}
+
+// withTimeout returns a context bounded by the stage timeout, if any.
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// stageError describes why a stage failed, naming the timeout
+// when the stage exceeded it.
+func stageError(stage string, timeout time.Duration, err error) error {
+	if timeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%s: timed out after %s: %w", stage, timeout, err)
+	}
+	return fmt.Errorf("%s: %w", stage, err)
+}
@@ -0,0 +1,203 @@
+// Package compat compares two generations of a CRD, or two JSON
+// schemas, classifying every change as compatible or breaking for
+// the custom resources validated by the previous generation.
+package compat
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krateoplatformops/crdgen/internal/crd"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Severity classifies a change.
+type Severity string
+
+const (
+	// Compatible changes keep valid every object valid before,
+	// e.g. a new optional field or a relaxed constraint.
+	Compatible Severity = "compatible"
+	// Breaking changes may make invalid (or change the meaning of)
+	// existing objects, e.g. a removed field or a type change.
+	Breaking Severity = "breaking"
+)
+
+// Change is a difference between two generations.
+type Change struct {
+	// Version is the CRD version of the change, empty for the
+	// changes of the CRD itself and when comparing JSON schemas.
+	Version string
+	// Path is the JSON path of the property, e.g. 'spec.address.zip';
+	// array items are marked by '[*]' and map values by '.*'.
+	Path     string
+	Severity Severity
+	Message  string
+}
+
+func (c Change) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(c.Severity))
+	if len(c.Version) > 0 {
+		fmt.Fprintf(&sb, ": %s", c.Version)
+	}
+	if len(c.Path) > 0 {
+		fmt.Fprintf(&sb, ": %s", c.Path)
+	}
+	fmt.Fprintf(&sb, ": %s", c.Message)
+	return sb.String()
+}
+
+// Bump is the version change suggested by a report.
+type Bump string
+
+const (
+	// BumpNone: nothing changed affecting the validation.
+	BumpNone Bump = "none"
+	// BumpMinor: compatible changes only, the API version can be kept
+	// and a chart shipping the CRD needs a minor version bump.
+	BumpMinor Bump = "minor"
+	// BumpMajor: breaking changes, a new API version is needed (e.g.
+	// v1alpha2) and a chart shipping the CRD needs a major version bump.
+	BumpMajor Bump = "major"
+)
+
+// Report lists the changes in a deterministic order.
+type Report struct {
+	Changes []Change
+}
+
+// Breaking reports whether any change is breaking.
+func (r Report) Breaking() bool {
+	for _, el := range r.Changes {
+		if el.Severity == Breaking {
+			return true
+		}
+	}
+	return false
+}
+
+// Bump returns the suggested version bump.
+func (r Report) Bump() Bump {
+	switch {
+	case r.Breaking():
+		return BumpMajor
+	case len(r.Changes) > 0:
+		return BumpMinor
+	default:
+		return BumpNone
+	}
+}
+
+// CRDs compares two generations of the same CRD.
+func CRDs(prev, next *apiextensionsv1.CustomResourceDefinition) Report {
+	d := &differ{}
+
+	if prev.Spec.Group != next.Spec.Group {
+		d.breaking("", "", "group changed from '%s' to '%s'", prev.Spec.Group, next.Spec.Group)
+	}
+	if prev.Spec.Names.Kind != next.Spec.Names.Kind {
+		d.breaking("", "", "kind changed from '%s' to '%s'", prev.Spec.Names.Kind, next.Spec.Names.Kind)
+	}
+	if prev.Spec.Names.Plural != next.Spec.Names.Plural {
+		d.breaking("", "", "plural name changed from '%s' to '%s'", prev.Spec.Names.Plural, next.Spec.Names.Plural)
+	}
+	if prev.Spec.Scope != next.Spec.Scope {
+		d.breaking("", "", "scope changed from %s to %s", prev.Spec.Scope, next.Spec.Scope)
+	}
+
+	nextVersions := map[string]*apiextensionsv1.CustomResourceDefinitionVersion{}
+	for i := range next.Spec.Versions {
+		nextVersions[next.Spec.Versions[i].Name] = &next.Spec.Versions[i]
+	}
+
+	prevVersions := map[string]bool{}
+	for i := range prev.Spec.Versions {
+		pv := &prev.Spec.Versions[i]
+		prevVersions[pv.Name] = true
+
+		nv, ok := nextVersions[pv.Name]
+		switch {
+		case !ok:
+			d.breaking(pv.Name, "", "version removed")
+			continue
+		case pv.Served && !nv.Served:
+			d.breaking(pv.Name, "", "version no longer served")
+		case !pv.Served && nv.Served:
+			d.compatible(pv.Name, "", "version served")
+		}
+
+		if pv.Storage != nv.Storage && nv.Storage {
+			d.compatible(pv.Name, "", "storage version")
+		}
+
+		if !pv.Deprecated && nv.Deprecated {
+			d.compatible(pv.Name, "", "version deprecated")
+		}
+
+		d.version = pv.Name
+		d.schema("", schemaOf(pv), schemaOf(nv))
+		d.version = ""
+
+		if hasStatus(pv) != hasStatus(nv) {
+			d.breaking(pv.Name, "", "status subresource %s", addedOrRemoved(hasStatus(nv)))
+		}
+	}
+
+	for _, nv := range next.Spec.Versions {
+		if !prevVersions[nv.Name] {
+			d.compatible(nv.Name, "", "version added")
+		}
+	}
+
+	return Report{Changes: d.changes}
+}
+
+// Schemas compares two JSON schemas, e.g. two 'values.schema.json' of
+// a Helm chart; YAML documents are accepted too. The schemas are compared
+// as Generate turns them in the spec of the CRD: the references resolved
+// and the keywords the CRDs do not carry dropped.
+func Schemas(prev, next []byte) (Report, error) {
+	ps, err := parseSchema(prev)
+	if err != nil {
+		return Report{}, fmt.Errorf("previous JSON schema: %w", err)
+	}
+
+	ns, err := parseSchema(next)
+	if err != nil {
+		return Report{}, fmt.Errorf("next JSON schema: %w", err)
+	}
+
+	d := &differ{}
+	d.schema("", ps, ns)
+
+	return Report{Changes: d.changes}, nil
+}
+
+func parseSchema(data []byte) (*apiextensionsv1.JSONSchemaProps, error) {
+	data, err := yaml.YAMLToJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return crd.Schema(data)
+}
+
+func schemaOf(ver *apiextensionsv1.CustomResourceDefinitionVersion) *apiextensionsv1.JSONSchemaProps {
+	if ver.Schema == nil {
+		return nil
+	}
+	return ver.Schema.OpenAPIV3Schema
+}
+
+func hasStatus(ver *apiextensionsv1.CustomResourceDefinitionVersion) bool {
+	return ver.Subresources != nil && ver.Subresources.Status != nil
+}
+
+func addedOrRemoved(added bool) string {
+	if added {
+		return "added"
+	}
+	return "removed"
+}
@@ -0,0 +1,316 @@
+package compat_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/compat"
+	"github.com/krateoplatformops/crdgen/getter"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestSchemas(t *testing.T) {
+	const prev = `{
+		"type": "object",
+		"required": ["name"],
+		"properties": {
+			"name": {"type": "string", "maxLength": 63},
+			"replicas": {"type": "integer", "minimum": 1, "maximum": 10},
+			"mode": {"type": "string", "enum": ["fast", "safe"]},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"legacy": {"type": "boolean"},
+			"port": {"type": "integer"}
+		}
+	}`
+
+	tests := []struct {
+		name string
+		next string
+		want []string
+		bump compat.Bump
+	}{
+		{
+			name: "identical",
+			next: prev,
+			bump: compat.BumpNone,
+		},
+		{
+			name: "compatible",
+			next: `
+type: object
+properties:
+  name: {type: string, maxLength: 253, description: the name}
+  replicas: {type: integer, minimum: 0, maximum: 10}
+  mode: {type: string, enum: [fast, safe, balanced]}
+  tags: {type: array, items: {type: string}}
+  legacy: {type: boolean}
+  port: {type: integer}
+  zone: {type: string}
+`,
+			want: []string{
+				"compatible: mode: enum values added: [\"balanced\"]",
+				"compatible: name: field no longer required",
+				"compatible: replicas: minimum lowered from 1 to 0",
+				"compatible: zone: optional field added",
+			},
+			bump: compat.BumpMinor,
+		},
+		{
+			name: "breaking",
+			next: `{
+				"type": "object",
+				"required": ["name", "region"],
+				"properties": {
+					"name": {"type": "string", "maxLength": 63, "pattern": "^[a-z]+$"},
+					"replicas": {"type": "integer", "minimum": 1, "maximum": 5},
+					"mode": {"type": "string", "enum": ["fast"]},
+					"tags": {"type": "array", "items": {"type": "integer"}},
+					"port": {"type": "string"},
+					"region": {"type": "string"}
+				}
+			}`,
+			want: []string{
+				"breaking: legacy: field removed",
+				"breaking: mode: enum values removed: [\"safe\"]",
+				"breaking: name: pattern set to '^[a-z]+$'",
+				"breaking: port: type changed from integer to string",
+				"breaking: region: required field added",
+				"breaking: replicas: maximum lowered from 10 to 5",
+				"breaking: tags[*]: type changed from string to integer",
+			},
+			bump: compat.BumpMajor,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := compat.Schemas([]byte(prev), []byte(tc.next))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			for _, el := range res.Changes {
+				got = append(got, el.String())
+			}
+
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(tc.want, "\n"), strings.Join(got, "\n"))
+			}
+
+			if res.Bump() != tc.bump {
+				t.Errorf("expected bump %s, got %s", tc.bump, res.Bump())
+			}
+		})
+	}
+}
+
+func TestCRDsExtensions(t *testing.T) {
+	tests := []struct {
+		name string
+		prev string
+		next string
+		want []string
+	}{
+		{
+			name: "validation rules",
+			prev: `{"type": "object", "x-kubernetes-validations": [{"rule": "self.a < self.b"}, {"rule": "has(self.a)"}]}`,
+			next: `{"type": "object", "x-kubernetes-validations": [{"rule": "has(self.a)", "message": "a is required"}, {"rule": "self.a > 0"}]}`,
+			want: []string{
+				"compatible: v1: validation rule removed: self.a < self.b",
+				"breaking: v1: validation rule changed: has(self.a)",
+				"breaking: v1: validation rule added: self.a > 0",
+			},
+		},
+		{
+			name: "combinators added",
+			prev: `{"type": "object"}`,
+			next: `{"type": "object", "anyOf": [{"required": ["a"]}], "not": {"required": ["b"]}}`,
+			want: []string{
+				"breaking: v1: anyOf added",
+				"breaking: v1: not added",
+			},
+		},
+		{
+			name: "combinators removed and changed",
+			prev: `{"type": "object", "oneOf": [{"required": ["a"]}], "allOf": [{"required": ["a"]}]}`,
+			next: `{"type": "object", "allOf": [{"required": ["b"]}]}`,
+			want: []string{
+				"breaking: v1: allOf changed",
+				"compatible: v1: oneOf removed",
+			},
+		},
+		{
+			name: "int or string",
+			prev: `{"type": "object", "properties": {"port": {"x-kubernetes-int-or-string": true}}}`,
+			next: `{"type": "object", "properties": {"port": {"type": "integer"}}}`,
+			want: []string{
+				"breaking: v1: port: type set to integer",
+				"breaking: v1: port: x-kubernetes-int-or-string unset",
+			},
+		},
+		{
+			name: "list type",
+			prev: `{"type": "array", "items": {"type": "string"}, "x-kubernetes-list-type": "atomic"}`,
+			next: `{"type": "array", "items": {"type": "string"}, "x-kubernetes-list-type": "set"}`,
+			want: []string{
+				"breaking: v1: x-kubernetes-list-type changed from 'atomic' to 'set'",
+			},
+		},
+		{
+			name: "embedded resource",
+			prev: `{"type": "object", "x-kubernetes-preserve-unknown-fields": true}`,
+			next: `{"type": "object", "x-kubernetes-preserve-unknown-fields": true, "x-kubernetes-embedded-resource": true}`,
+			want: []string{
+				"breaking: v1: x-kubernetes-embedded-resource changed from 'false' to 'true'",
+			},
+		},
+		{
+			name: "unrecognized",
+			prev: `{"type": "object", "definitions": {"a": {"type": "string"}}}`,
+			next: `{"type": "object", "definitions": {"a": {"type": "integer"}}, "description": "docs only"}`,
+			want: []string{
+				"breaking: v1: unrecognized change of definitions",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res := compat.CRDs(crdOf(t, tc.prev), crdOf(t, tc.next))
+
+			got := []string{}
+			for _, el := range res.Changes {
+				got = append(got, el.String())
+			}
+
+			if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+				t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(tc.want, "\n"), strings.Join(got, "\n"))
+			}
+		})
+	}
+}
+
+// crdOf returns a CRD of a single version with the schema.
+func crdOf(t *testing.T, schema string) *apiextensionsv1.CustomResourceDefinition {
+	t.Helper()
+
+	props := &apiextensionsv1.JSONSchemaProps{}
+	if err := json.Unmarshal([]byte(schema), props); err != nil {
+		t.Fatal(err)
+	}
+
+	return &apiextensionsv1.CustomResourceDefinition{
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:   "v1",
+				Served: true,
+				Schema: &apiextensionsv1.CustomResourceValidation{OpenAPIV3Schema: props},
+			}},
+		},
+	}
+}
+
+func TestSchemasReferences(t *testing.T) {
+	const prev = `{
+		"type": "object",
+		"definitions": {
+			"port": {"type": "integer"},
+			"address": {"type": "object", "properties": {"host": {"type": "string"}}}
+		},
+		"properties": {
+			"port": {"$ref": "#/definitions/port"},
+			"address": {"$ref": "#/definitions/address"}
+		}
+	}`
+
+	const next = `{
+		"type": "object",
+		"definitions": {
+			"port": {"type": "string"},
+			"address": {"type": "object", "properties": {"host": {"type": "string"}, "zone": {"type": "string"}}}
+		},
+		"properties": {
+			"port": {"$ref": "#/definitions/port"},
+			"address": {"$ref": "#/definitions/address"}
+		}
+	}`
+
+	res, err := compat.Schemas([]byte(prev), []byte(next))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := []string{}
+	for _, el := range res.Changes {
+		got = append(got, el.String())
+	}
+
+	want := []string{
+		"compatible: address.zone: optional field added",
+		"breaking: port: type changed from integer to string",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestSchemasTypeArray(t *testing.T) {
+	const prev = `{"type": "object", "properties": {"name": {"type": "string"}}}`
+	const next = `{"type": "object", "properties": {"name": {"type": ["string", "null"]}}}`
+
+	// rejected as Generate does: a CRD property has a single type
+	_, err := compat.Schemas([]byte(prev), []byte(next))
+	if err == nil || !strings.Contains(err.Error(), "multiple types in schema") {
+		t.Errorf("expected a multiple types error, got: %v", err)
+	}
+}
+
+func TestCRDs(t *testing.T) {
+	generate := func(spec string, managed bool) crdgen.Result {
+		res := crdgen.Generate(context.TODO(), crdgen.Options{
+			GVK: schema.GroupVersionKind{
+				Group:   "example.org",
+				Version: "v1alpha1",
+				Kind:    "Xapp",
+			},
+			Native:               true,
+			Managed:              managed,
+			SpecJsonSchemaGetter: getter.Bytes(spec),
+		})
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		return res
+	}
+
+	prev := generate(`{"type": "object", "properties": {"name": {"type": "string"}}}`, true)
+	next := generate(`{"type": "object", "properties": {"name": {"type": "string"}, "size": {"type": "integer"}}}`, false)
+
+	res := compat.CRDs(prev.CRDs["xapps.example.org"], next.CRDs["xapps.example.org"])
+	if !res.Breaking() {
+		t.Fatalf("expected breaking changes, got: %v", res.Changes)
+	}
+
+	found := map[string]bool{}
+	for _, el := range res.Changes {
+		if el.Version != "v1alpha1" {
+			t.Errorf("expected changes of version v1alpha1, got: %v", el)
+		}
+		found[el.Path+": "+el.Message] = true
+	}
+
+	for _, want := range []string{
+		"spec.size: optional field added",
+		": status subresource removed",
+		"status: field removed",
+	} {
+		if !found[want] {
+			t.Errorf("expected change '%s', got: %v", want, res.Changes)
+		}
+	}
+}
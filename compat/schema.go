@@ -0,0 +1,475 @@
+package compat
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// differ collects the changes between two schemas.
+type differ struct {
+	version string
+	changes []Change
+}
+
+func (d *differ) add(sev Severity, version, path, format string, args ...any) {
+	d.changes = append(d.changes, Change{
+		Version:  version,
+		Path:     path,
+		Severity: sev,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (d *differ) breaking(version, path, format string, args ...any) {
+	d.add(Breaking, version, path, format, args...)
+}
+
+func (d *differ) compatible(version, path, format string, args ...any) {
+	d.add(Compatible, version, path, format, args...)
+}
+
+// schema compares the schemas of the property at path, recursively.
+func (d *differ) schema(path string, prev, next *apiextensionsv1.JSONSchemaProps) {
+	switch {
+	case prev == nil && next == nil:
+		return
+	case prev == nil:
+		d.breaking(d.version, path, "schema added")
+		return
+	case next == nil:
+		d.compatible(d.version, path, "schema removed")
+		return
+	}
+
+	if prev.Type != next.Type {
+		switch {
+		case len(prev.Type) == 0:
+			d.breaking(d.version, path, "type set to %s", next.Type)
+		case len(next.Type) == 0:
+			d.compatible(d.version, path, "type %s removed", prev.Type)
+		default:
+			d.breaking(d.version, path, "type changed from %s to %s", prev.Type, next.Type)
+			// nothing else is comparable
+			return
+		}
+	}
+
+	d.flag(path, "nullable", prev.Nullable, next.Nullable)
+	d.flag(path, "x-kubernetes-preserve-unknown-fields",
+		ptrBool(prev.XPreserveUnknownFields), ptrBool(next.XPreserveUnknownFields))
+
+	if prev.Format != next.Format {
+		switch {
+		case len(next.Format) == 0:
+			d.compatible(d.version, path, "format '%s' removed", prev.Format)
+		case len(prev.Format) == 0:
+			d.breaking(d.version, path, "format set to '%s'", next.Format)
+		default:
+			d.breaking(d.version, path, "format changed from '%s' to '%s'", prev.Format, next.Format)
+		}
+	}
+
+	if prev.Pattern != next.Pattern {
+		switch {
+		case len(next.Pattern) == 0:
+			d.compatible(d.version, path, "pattern '%s' removed", prev.Pattern)
+		case len(prev.Pattern) == 0:
+			d.breaking(d.version, path, "pattern set to '%s'", next.Pattern)
+		default:
+			d.breaking(d.version, path, "pattern changed from '%s' to '%s'", prev.Pattern, next.Pattern)
+		}
+	}
+
+	d.enum(path, prev.Enum, next.Enum)
+
+	d.lowerBound(path, "minimum", prev.Minimum, next.Minimum, prev.ExclusiveMinimum, next.ExclusiveMinimum)
+	d.upperBound(path, "maximum", prev.Maximum, next.Maximum, prev.ExclusiveMaximum, next.ExclusiveMaximum)
+	d.lowerBound(path, "minLength", intBound(prev.MinLength), intBound(next.MinLength), false, false)
+	d.upperBound(path, "maxLength", intBound(prev.MaxLength), intBound(next.MaxLength), false, false)
+	d.lowerBound(path, "minItems", intBound(prev.MinItems), intBound(next.MinItems), false, false)
+	d.upperBound(path, "maxItems", intBound(prev.MaxItems), intBound(next.MaxItems), false, false)
+	d.lowerBound(path, "minProperties", intBound(prev.MinProperties), intBound(next.MinProperties), false, false)
+	d.upperBound(path, "maxProperties", intBound(prev.MaxProperties), intBound(next.MaxProperties), false, false)
+
+	if prev.MultipleOf == nil && next.MultipleOf != nil ||
+		prev.MultipleOf != nil && next.MultipleOf != nil && *prev.MultipleOf != *next.MultipleOf {
+		d.breaking(d.version, path, "multipleOf set to %v", *next.MultipleOf)
+	} else if prev.MultipleOf != nil && next.MultipleOf == nil {
+		d.compatible(d.version, path, "multipleOf removed")
+	}
+
+	if !prev.UniqueItems && next.UniqueItems {
+		d.breaking(d.version, path, "items must be unique")
+	} else if prev.UniqueItems && !next.UniqueItems {
+		d.compatible(d.version, path, "items no longer unique")
+	}
+
+	if !jsonEqual(prev.Default, next.Default) {
+		// only the objects created from now on get the new default
+		d.compatible(d.version, path, "default changed from %s to %s", jsonString(prev.Default), jsonString(next.Default))
+	}
+
+	d.properties(path, prev, next)
+
+	if prev.Items != nil || next.Items != nil {
+		d.schema(path+"[*]", itemsOf(prev.Items), itemsOf(next.Items))
+	}
+
+	d.additionalProperties(path, prev.AdditionalProperties, next.AdditionalProperties)
+
+	d.validations(path, prev.XValidations, next.XValidations)
+
+	d.combinator(path, "allOf", prev.AllOf, next.AllOf)
+	d.combinator(path, "anyOf", prev.AnyOf, next.AnyOf)
+	d.combinator(path, "oneOf", prev.OneOf, next.OneOf)
+	d.combinator(path, "not", notOf(prev), notOf(next))
+
+	d.flag(path, "x-kubernetes-int-or-string", prev.XIntOrString, next.XIntOrString)
+
+	d.extension(path, "x-kubernetes-embedded-resource",
+		strconv.FormatBool(prev.XEmbeddedResource), strconv.FormatBool(next.XEmbeddedResource))
+	d.extension(path, "x-kubernetes-list-type", ptrString(prev.XListType), ptrString(next.XListType))
+	d.extension(path, "x-kubernetes-list-map-keys",
+		strings.Join(prev.XListMapKeys, ","), strings.Join(next.XListMapKeys, ","))
+	d.extension(path, "x-kubernetes-map-type", ptrString(prev.XMapType), ptrString(next.XMapType))
+
+	if keys := changedKeys(rest(prev), rest(next)); len(keys) > 0 {
+		d.breaking(d.version, path, "unrecognized change of %s", strings.Join(keys, ", "))
+	}
+}
+
+// validations compares the CEL rules: the added and the changed ones
+// may reject the objects stored so far.
+func (d *differ) validations(path string, prev, next apiextensionsv1.ValidationRules) {
+	find := func(all apiextensionsv1.ValidationRules, rule string) (apiextensionsv1.ValidationRule, bool) {
+		for _, el := range all {
+			if el.Rule == rule {
+				return el, true
+			}
+		}
+		return apiextensionsv1.ValidationRule{}, false
+	}
+
+	for _, el := range prev {
+		if _, ok := find(next, el.Rule); !ok {
+			d.compatible(d.version, path, "validation rule removed: %s", el.Rule)
+		}
+	}
+
+	for _, el := range next {
+		old, ok := find(prev, el.Rule)
+		switch {
+		case !ok:
+			d.breaking(d.version, path, "validation rule added: %s", el.Rule)
+		case !reflect.DeepEqual(old, el):
+			d.breaking(d.version, path, "validation rule changed: %s", el.Rule)
+		}
+	}
+}
+
+// combinator compares the subschemas of allOf, anyOf, oneOf or not,
+// whose changes are not analyzed further.
+func (d *differ) combinator(path, name string, prev, next []apiextensionsv1.JSONSchemaProps) {
+	switch {
+	case len(prev) == 0 && len(next) == 0:
+		return
+	case len(prev) == 0:
+		d.breaking(d.version, path, "%s added", name)
+	case len(next) == 0:
+		d.compatible(d.version, path, "%s removed", name)
+	case !reflect.DeepEqual(prev, next):
+		d.breaking(d.version, path, "%s changed", name)
+	}
+}
+
+// extension compares a Kubernetes extension changing how the values
+// are validated or merged: any change is breaking.
+func (d *differ) extension(path, name, prev, next string) {
+	switch {
+	case prev == next:
+		return
+	case len(prev) == 0:
+		d.breaking(d.version, path, "%s set to '%s'", name, next)
+	case len(next) == 0:
+		d.breaking(d.version, path, "%s '%s' removed", name, prev)
+	default:
+		d.breaking(d.version, path, "%s changed from '%s' to '%s'", name, prev, next)
+	}
+}
+
+// properties compares the properties and the required ones.
+func (d *differ) properties(path string, prev, next *apiextensionsv1.JSONSchemaProps) {
+	names := []string{}
+	for k := range prev.Properties {
+		names = append(names, k)
+	}
+	for k := range next.Properties {
+		if _, ok := prev.Properties[k]; !ok {
+			names = append(names, k)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		sub := join(path, name)
+		ps, inPrev := prev.Properties[name]
+		ns, inNext := next.Properties[name]
+		required := slices.Contains(next.Required, name)
+
+		switch {
+		case !inNext:
+			d.breaking(d.version, sub, "field removed")
+		case !inPrev && required:
+			d.breaking(d.version, sub, "required field added")
+		case !inPrev:
+			d.compatible(d.version, sub, "optional field added")
+		default:
+			if !slices.Contains(prev.Required, name) && required {
+				d.breaking(d.version, sub, "field now required")
+			} else if slices.Contains(prev.Required, name) && !required {
+				d.compatible(d.version, sub, "field no longer required")
+			}
+
+			d.schema(sub, &ps, &ns)
+		}
+	}
+}
+
+// additionalProperties compares the schemas of the map values.
+func (d *differ) additionalProperties(path string, prev, next *apiextensionsv1.JSONSchemaPropsOrBool) {
+	allows := func(el *apiextensionsv1.JSONSchemaPropsOrBool) bool {
+		return el != nil && (el.Allows || el.Schema != nil)
+	}
+
+	switch {
+	case allows(prev) && !allows(next):
+		d.breaking(d.version, path, "additional properties no longer allowed")
+		return
+	case !allows(prev) && allows(next):
+		d.compatible(d.version, path, "additional properties allowed")
+		return
+	case !allows(prev):
+		return
+	}
+
+	switch {
+	case prev.Schema == nil && next.Schema != nil:
+		d.breaking(d.version, path+".*", "schema added")
+	case prev.Schema != nil && next.Schema == nil:
+		d.compatible(d.version, path+".*", "schema removed")
+	default:
+		d.schema(path+".*", prev.Schema, next.Schema)
+	}
+}
+
+// enum compares the allowed values.
+func (d *differ) enum(path string, prev, next []apiextensionsv1.JSON) {
+	has := func(all []apiextensionsv1.JSON, el apiextensionsv1.JSON) bool {
+		return slices.ContainsFunc(all, func(v apiextensionsv1.JSON) bool {
+			return jsonEqual(&v, &el)
+		})
+	}
+
+	switch {
+	case len(prev) == 0 && len(next) == 0:
+		return
+	case len(next) == 0:
+		d.compatible(d.version, path, "enum removed")
+		return
+	case len(prev) == 0:
+		d.breaking(d.version, path, "enum added: %s", jsonValues(next))
+		return
+	}
+
+	removed := []apiextensionsv1.JSON{}
+	for _, el := range prev {
+		if !has(next, el) {
+			removed = append(removed, el)
+		}
+	}
+
+	added := []apiextensionsv1.JSON{}
+	for _, el := range next {
+		if !has(prev, el) {
+			added = append(added, el)
+		}
+	}
+
+	if len(removed) > 0 {
+		d.breaking(d.version, path, "enum values removed: %s", jsonValues(removed))
+	}
+	if len(added) > 0 {
+		d.compatible(d.version, path, "enum values added: %s", jsonValues(added))
+	}
+}
+
+// flag compares a boolean relaxing the schema when set.
+func (d *differ) flag(path, name string, prev, next bool) {
+	switch {
+	case prev && !next:
+		d.breaking(d.version, path, "%s unset", name)
+	case !prev && next:
+		d.compatible(d.version, path, "%s set", name)
+	}
+}
+
+// lowerBound compares a minimum: raising it is breaking.
+func (d *differ) lowerBound(path, name string, prev, next *float64, prevExcl, nextExcl bool) {
+	switch {
+	case prev == nil && next == nil:
+		return
+	case prev == nil:
+		d.breaking(d.version, path, "%s set to %v", name, *next)
+	case next == nil:
+		d.compatible(d.version, path, "%s removed", name)
+	case *next > *prev || *next == *prev && nextExcl && !prevExcl:
+		d.breaking(d.version, path, "%s raised from %v to %v", name, *prev, *next)
+	case *next < *prev || *next == *prev && prevExcl && !nextExcl:
+		d.compatible(d.version, path, "%s lowered from %v to %v", name, *prev, *next)
+	}
+}
+
+// upperBound compares a maximum: lowering it is breaking.
+func (d *differ) upperBound(path, name string, prev, next *float64, prevExcl, nextExcl bool) {
+	switch {
+	case prev == nil && next == nil:
+		return
+	case prev == nil:
+		d.breaking(d.version, path, "%s set to %v", name, *next)
+	case next == nil:
+		d.compatible(d.version, path, "%s removed", name)
+	case *next < *prev || *next == *prev && nextExcl && !prevExcl:
+		d.breaking(d.version, path, "%s lowered from %v to %v", name, *prev, *next)
+	case *next > *prev || *next == *prev && prevExcl && !nextExcl:
+		d.compatible(d.version, path, "%s raised from %v to %v", name, *prev, *next)
+	}
+}
+
+// rest returns the fields of the schema the differ does not compare,
+// the documentation ones and the subschemas excluded.
+func rest(s *apiextensionsv1.JSONSchemaProps) *apiextensionsv1.JSONSchemaProps {
+	res := s.DeepCopy()
+
+	res.Description, res.Title, res.Example, res.ExternalDocs = "", "", nil, nil
+
+	res.Type, res.Format, res.Pattern, res.Enum, res.Default = "", "", "", nil, nil
+	res.Nullable, res.XPreserveUnknownFields, res.XIntOrString = false, nil, false
+	res.Maximum, res.ExclusiveMaximum, res.Minimum, res.ExclusiveMinimum = nil, false, nil, false
+	res.MaxLength, res.MinLength, res.MaxItems, res.MinItems = nil, nil, nil, nil
+	res.MaxProperties, res.MinProperties, res.MultipleOf, res.UniqueItems = nil, nil, nil, false
+	res.Properties, res.Required, res.Items, res.AdditionalProperties = nil, nil, nil, nil
+	res.AllOf, res.AnyOf, res.OneOf, res.Not, res.XValidations = nil, nil, nil, nil, nil
+	res.XEmbeddedResource, res.XListType, res.XListMapKeys, res.XMapType = false, nil, nil, nil
+
+	return res
+}
+
+// changedKeys returns the JSON keys whose values differ.
+func changedKeys(prev, next *apiextensionsv1.JSONSchemaProps) []string {
+	fields := func(s *apiextensionsv1.JSONSchemaProps) map[string]any {
+		res := map[string]any{}
+		dat, _ := json.Marshal(s)
+		json.Unmarshal(dat, &res)
+		return res
+	}
+
+	pf, nf := fields(prev), fields(next)
+
+	res := []string{}
+	for k, v := range pf {
+		if !reflect.DeepEqual(v, nf[k]) {
+			res = append(res, k)
+		}
+	}
+	for k := range nf {
+		if _, ok := pf[k]; !ok {
+			res = append(res, k)
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func notOf(s *apiextensionsv1.JSONSchemaProps) []apiextensionsv1.JSONSchemaProps {
+	if s.Not == nil {
+		return nil
+	}
+	return []apiextensionsv1.JSONSchemaProps{*s.Not}
+}
+
+func ptrString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func join(path, name string) string {
+	if len(path) == 0 {
+		return name
+	}
+	return path + "." + name
+}
+
+func itemsOf(el *apiextensionsv1.JSONSchemaPropsOrArray) *apiextensionsv1.JSONSchemaProps {
+	if el == nil {
+		return nil
+	}
+	if el.Schema != nil {
+		return el.Schema
+	}
+	if len(el.JSONSchemas) > 0 {
+		return &el.JSONSchemas[0]
+	}
+	return nil
+}
+
+func ptrBool(b *bool) bool {
+	return b != nil && *b
+}
+
+func intBound(n *int64) *float64 {
+	if n == nil {
+		return nil
+	}
+	f := float64(*n)
+	return &f
+}
+
+// jsonEqual compares two JSON values semantically.
+func jsonEqual(a, b *apiextensionsv1.JSON) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	var va, vb any
+	if json.Unmarshal(a.Raw, &va) != nil || json.Unmarshal(b.Raw, &vb) != nil {
+		return string(a.Raw) == string(b.Raw)
+	}
+
+	ja, _ := json.Marshal(va)
+	jb, _ := json.Marshal(vb)
+	return string(ja) == string(jb)
+}
+
+func jsonString(v *apiextensionsv1.JSON) string {
+	if v == nil {
+		return "none"
+	}
+	return string(v.Raw)
+}
+
+func jsonValues(all []apiextensionsv1.JSON) string {
+	res := make([]string, 0, len(all))
+	for _, el := range all {
+		res = append(res, string(el.Raw))
+	}
+	return "[" + strings.Join(res, ", ") + "]"
+}
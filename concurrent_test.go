@@ -0,0 +1,170 @@
+package crdgen_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestConcurrentGenerate(t *testing.T) {
+	const total = 48
+
+	results := make([]crdgen.Result, total)
+
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			results[i] = crdgen.Generate(context.Background(), crdgen.Options{
+				WorkDir: "xapp",
+				GVK: schema.GroupVersionKind{
+					Group:   "example.org",
+					Version: "v1alpha1",
+					Kind:    fmt.Sprintf("Xapp%d", i%4),
+				},
+				Native:  true,
+				Verbose: i%2 == 0,
+				SpecJsonSchemaGetter: getter.Bytes(`{
+					"type": "object",
+					"properties": {
+						"replicas": {"type": "integer", "default": 1}
+					}
+				}`),
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, res := range results {
+		if res.Err != nil {
+			t.Fatalf("generation #%d: %v", i, res.Err)
+		}
+
+		want := results[i%4]
+		if !bytes.Equal(res.Manifest, want.Manifest) {
+			t.Errorf("generation #%d: manifest differs from generation #%d", i, i%4)
+		}
+		if res.Digest != want.Digest {
+			t.Errorf("generation #%d: digest differs from generation #%d", i, i%4)
+		}
+	}
+}
+
+// fakeControllerGen writes the CRD of the kind XappN found in the
+// workdir and records the workdir in the dump directory.
+const fakeControllerGen = `#!/bin/sh
+pwd > "$CRDGEN_DUMP/$(basename "$PWD")"
+n=$(basename apis/*/*/xapp*_types.go _types.go)
+n=${n#xapp}
+mkdir -p crds
+cat > crds/crd.yaml <<EOF
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: xapp${n}s.example.org
+spec:
+  group: example.org
+  names:
+    kind: Xapp${n}
+    listKind: Xapp${n}List
+    plural: xapp${n}s
+    singular: xapp${n}
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    served: true
+    storage: true
+    schema:
+      openAPIV3Schema:
+        type: object
+EOF
+`
+
+func TestConcurrentControllerGen(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the controller-gen path with a fake toolchain")
+	}
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake binaries are shell scripts")
+	}
+
+	const total = 16
+
+	dir := t.TempDir()
+	gobin := filepath.Join(dir, "go")
+	if err := os.WriteFile(gobin, []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(dir, "controller-gen")
+	if err := os.WriteFile(bin, []byte(fakeControllerGen), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	dump := filepath.Join(dir, "workdirs")
+	if err := os.Mkdir(dump, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	results := make([]crdgen.Result, total)
+
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			results[i] = crdgen.Generate(context.Background(), crdgen.Options{
+				WorkDir: "xapp",
+				GVK: schema.GroupVersionKind{
+					Group:   "example.org",
+					Version: "v1alpha1",
+					Kind:    fmt.Sprintf("Xapp%d", i%4),
+				},
+				Verbose:              i%2 == 0,
+				SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+				Toolchain: crdgen.Toolchain{
+					Go:  gobin,
+					Env: []string{"CRDGEN_DUMP=" + dump},
+				},
+				ControllerGen: crdgen.ControllerGen{Binary: bin},
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	workdirs := map[string]bool{}
+	for i, res := range results {
+		if res.Err != nil {
+			t.Fatalf("generation #%d: %v", i, res.Err)
+		}
+		if workdirs[res.WorkDir] {
+			t.Errorf("generation #%d: workdir '%s' shared by concurrent generations", i, res.WorkDir)
+		}
+		workdirs[res.WorkDir] = true
+
+		kind := fmt.Sprintf("kind: Xapp%d\n", i%4)
+		if !strings.Contains(string(res.Manifest), kind) {
+			t.Errorf("generation #%d: expected manifest of %q, got:\n%s", i, kind, res.Manifest)
+		}
+	}
+
+	// every controller-gen run worked in its own workdir
+	files, err := os.ReadDir(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != total {
+		t.Errorf("expected %d distinct controller-gen workdirs, got %d", total, len(files))
+	}
+}
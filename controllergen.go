@@ -0,0 +1,66 @@
+package crdgen
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/krateoplatformops/crdgen/internal/coder"
+)
+
+// ControllerGen configures the controller-gen run in controller-gen mode.
+type ControllerGen struct {
+	// Binary is the path of a prebuilt controller-gen; by default
+	// controller-gen is built and run by 'go run' at the version of
+	// Dependencies.ControllerTools.
+	Binary string
+	// MaxDescLen, when set, truncates the descriptions of the schema
+	// longer than it; zero drops all of them.
+	MaxDescLen *int
+	// AllowDangerousTypes allows the float32 and float64 fields.
+	AllowDangerousTypes bool
+	// GenerateEmbeddedObjectMeta keeps the metadata (labels,
+	// annotations...) of the embedded object templates.
+	GenerateEmbeddedObjectMeta bool
+	// IgnoreUnexportedFields skips the unexported fields of the types.
+	IgnoreUnexportedFields bool
+	// SkipDeepCopy skips the generation of the DeepCopy methods,
+	// which only the Go module of a Scaffold needs.
+	SkipDeepCopy bool
+}
+
+// validate checks the generator options.
+func (c ControllerGen) validate() error {
+	if c.MaxDescLen != nil && *c.MaxDescLen < 0 {
+		return fmt.Errorf("invalid controller-gen maxDescLen %d: must not be negative", *c.MaxDescLen)
+	}
+	return nil
+}
+
+// crdOptions returns the options of the CRD generator.
+func (c ControllerGen) crdOptions() []string {
+	res := []string{}
+	if c.MaxDescLen != nil {
+		res = append(res, "maxDescLen="+strconv.Itoa(*c.MaxDescLen))
+	}
+	if c.AllowDangerousTypes {
+		res = append(res, "allowDangerousTypes=true")
+	}
+	if c.GenerateEmbeddedObjectMeta {
+		res = append(res, "generateEmbeddedObjectMeta=true")
+	}
+	if c.IgnoreUnexportedFields {
+		res = append(res, "ignoreUnexportedFields=true")
+	}
+	return res
+}
+
+// command returns the command running controller-gen in the workdir
+// root, either the prebuilt binary or 'go run'.
+func (c ControllerGen) command(gobin string, cfg coder.Options) (string, []string) {
+	args := coder.ControllerGenArgs(cfg, ".")
+	if len(c.Binary) > 0 {
+		return c.Binary, args
+	}
+
+	return gobin, append([]string{"run", "--tags", "generate", coder.PkgControllerGen}, args...)
+}
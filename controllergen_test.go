@@ -0,0 +1,150 @@
+package crdgen_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"github.com/krateoplatformops/crdgen/internal/ptr"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestControllerGenBinary(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake binaries are shell scripts")
+	}
+
+	dir := t.TempDir()
+
+	// the fake go binary succeeds, the fake controller-gen dumps its arguments and fails
+	gobin := filepath.Join(dir, "go")
+	if err := os.WriteFile(gobin, []byte("#!/bin/sh\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	bin := filepath.Join(dir, "controller-gen")
+	if err := os.WriteFile(bin, []byte("#!/bin/sh\necho \"$@\" > \"$CRDGEN_DUMP\"\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	dump := filepath.Join(dir, "args.txt")
+
+	tests := []struct {
+		name string
+		cfg  crdgen.ControllerGen
+		want string
+	}{
+		{
+			name: "default",
+			want: "object:headerFile=./hack/boilerplate.go.txt paths=./... crd:crdVersions=v1 output:artifacts:config=./crds",
+		},
+		{
+			name: "options",
+			cfg: crdgen.ControllerGen{
+				MaxDescLen:                 ptr.To(0),
+				AllowDangerousTypes:        true,
+				GenerateEmbeddedObjectMeta: true,
+				IgnoreUnexportedFields:     true,
+				SkipDeepCopy:               true,
+			},
+			want: "paths=./... crd:crdVersions=v1,maxDescLen=0,allowDangerousTypes=true,generateEmbeddedObjectMeta=true,ignoreUnexportedFields=true output:artifacts:config=./crds",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.cfg.Binary = bin
+
+			res := crdgen.Generate(context.Background(), crdgen.Options{
+				WorkDir: "binary",
+				GVK: schema.GroupVersionKind{
+					Group:   "example.org",
+					Version: "v1alpha1",
+					Kind:    "Xapp",
+				},
+				SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+				Toolchain: crdgen.Toolchain{
+					Go:  gobin,
+					Env: []string{"CRDGEN_DUMP=" + dump},
+				},
+				ControllerGen: tc.cfg,
+			})
+
+			var genErr *crdgen.ControllerGenError
+			if !errors.As(res.Err, &genErr) {
+				t.Fatalf("expected a controller-gen error, got: %v", res.Err)
+			}
+			if want := "performing '" + bin + " " + tc.want + "'"; !strings.Contains(genErr.Error(), want) {
+				t.Errorf("expected %q in the error, got: %v", want, genErr)
+			}
+
+			dat, err := os.ReadFile(dump)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.TrimSpace(string(dat)); got != tc.want {
+				t.Errorf("expected arguments:\n%s\ngot:\n%s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestControllerGenErrors(t *testing.T) {
+	tests := map[string]crdgen.Options{
+		"negative maxDescLen": {
+			ControllerGen: crdgen.ControllerGen{MaxDescLen: ptr.To(-1)},
+		},
+		"scaffold without deepcopy": {
+			ControllerGen: crdgen.ControllerGen{SkipDeepCopy: true},
+			Scaffold:      &crdgen.Scaffold{},
+		},
+	}
+
+	for name, opts := range tests {
+		opts.GVK = schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "Xapp"}
+		opts.SpecJsonSchemaGetter = getter.Bytes(`{"type": "object"}`)
+
+		res := crdgen.Generate(context.Background(), opts)
+		if res.Err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+		if len(res.Digest) > 0 {
+			t.Errorf("%s: expected an error before the generation", name)
+		}
+	}
+}
+
+func TestControllerGenGoRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake go binary is a shell script")
+	}
+
+	// the fake go binary tidies the module and fails running controller-gen
+	gobin := filepath.Join(t.TempDir(), "go")
+	if err := os.WriteFile(gobin, []byte("#!/bin/sh\n[ \"$1\" = run ] && exit 1\nexit 0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	res := crdgen.Generate(context.Background(), crdgen.Options{
+		WorkDir: "gorun",
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Xapp",
+		},
+		SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+		Toolchain:            crdgen.Toolchain{Go: gobin},
+	})
+
+	var genErr *crdgen.ControllerGenError
+	if !errors.As(res.Err, &genErr) {
+		t.Fatalf("expected a controller-gen error, got: %v", res.Err)
+	}
+	if want := "performing '" + gobin + " run --tags generate "; !strings.Contains(genErr.Error(), want) {
+		t.Errorf("expected %q in the error, got: %v", want, genErr)
+	}
+}
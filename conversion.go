@@ -0,0 +1,39 @@
+package crdgen
+
+import (
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// ConversionWebhook configures the webhook the API server calls
+// to convert the custom resources between the served versions.
+type ConversionWebhook struct {
+	// Service references the conversion webhook service.
+	Service apiextensionsv1.ServiceReference
+	// CABundle is a PEM encoded CA bundle used to validate
+	// the webhook server certificate.
+	CABundle []byte
+	// ConversionReviewVersions defaults to ["v1"].
+	ConversionReviewVersions []string
+}
+
+// withConversion sets the webhook conversion strategy in the CRD.
+func withConversion(cfg *ConversionWebhook) patchFunc {
+	return func(obj *apiextensionsv1.CustomResourceDefinition) {
+		svc := cfg.Service
+		reviewVersions := cfg.ConversionReviewVersions
+		if len(reviewVersions) == 0 {
+			reviewVersions = []string{"v1"}
+		}
+
+		obj.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{
+			Strategy: apiextensionsv1.WebhookConverter,
+			Webhook: &apiextensionsv1.WebhookConversion{
+				ClientConfig: &apiextensionsv1.WebhookClientConfig{
+					Service:  &svc,
+					CABundle: cfg.CABundle,
+				},
+				ConversionReviewVersions: reviewVersions,
+			},
+		}
+	}
+}
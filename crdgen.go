@@ -3,23 +3,38 @@ package crdgen
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"strings"
+	"testing/fstest"
+	"time"
 
+	"github.com/krateoplatformops/crdgen/cache"
 	"github.com/krateoplatformops/crdgen/internal/assets"
 	"github.com/krateoplatformops/crdgen/internal/coder"
+	"github.com/krateoplatformops/crdgen/internal/crd"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
 type JsonSchemaGetter interface {
-	Get() ([]byte, error)
+	Get(ctx context.Context) ([]byte, error)
+}
+
+// Timeouts bounds the duration of each generation stage;
+// a zero value means no timeout other than the context one.
+type Timeouts struct {
+	// Fetch bounds the retrieval of all the JSON schemas.
+	Fetch time.Duration
+	// Tidy bounds 'go mod tidy'.
+	Tidy time.Duration
+	// ControllerGen bounds the controller-gen run.
+	ControllerGen time.Duration
 }
 
 type Options struct {
@@ -30,156 +45,432 @@ type Options struct {
 	StatusJsonSchemaGetter JsonSchemaGetter
 	Managed                bool
 	Verbose                bool
+	// Native builds the CRD in-process from the JSON schemas,
+	// without requiring a Go toolchain nor network access.
+	Native bool
+	// Kinds, when not empty, lists all the kinds generated in the same
+	// Go module, each one resulting in its own CRD; GVK, Categories,
+	// the schema getters, Managed, Versions, Scope, the names and
+	// Conversion above are then ignored.
+	Kinds []Kind
+	// Versions, when not empty, lists all the versions served by the CRD;
+	// GVK.Version and the schema getters above are then ignored.
+	Versions []Version
+	// Scope of the custom resources, Namespaced (default) or Cluster.
+	Scope apiextensionsv1.ResourceScope
+	// Plural and Singular are the lowercase resource names, by default
+	// derived from the kind using the English pluralization rules.
+	Plural   string
+	Singular string
+	// ShortNames are the short aliases of the resource, e.g. "xa".
+	ShortNames []string
+	// ListKind defaults to the kind followed by "List".
+	ListKind string
+	// Timeouts bounds the generation stages.
+	Timeouts Timeouts
+	// Offline, when set, generates without network access resolving
+	// the dependencies from a module cache or a vendor directory.
+	Offline *Offline
+	// Cache, when set, stores the manifests by digest returning
+	// the stored manifest when the same input is generated again.
+	Cache cache.Cache
+	// Conversion, when set, makes the API server convert the custom
+	// resources between versions calling the specified webhook.
+	Conversion *ConversionWebhook
+	// Scaffold, when set, exports the generated Go module;
+	// it requires the controller-gen mode and bypasses Cache.
+	Scaffold *Scaffold
+	// Labels and Annotations are added to the metadata of every CRD,
+	// those of a Kind win; the groups ending in 'k8s.io' or in
+	// 'kubernetes.io' require the 'api-approved.kubernetes.io' annotation.
+	Labels      map[string]string
+	Annotations map[string]string
+	// Provenance, when set, stamps every CRD with the annotations
+	// recording how it was generated; they are never cached.
+	Provenance *Provenance
+	// Dependencies overrides the versions required by the generated
+	// Go module in controller-gen mode.
+	Dependencies Dependencies
+	// Boilerplate heads the files generated by controller-gen, by
+	// default the Krateo copyright; it must be made of Go comments.
+	Boilerplate string
+	// Toolchain configures the go commands run in controller-gen mode.
+	Toolchain Toolchain
+	// Templates, when set, holds the templates replacing the embedded
+	// ones with the same file name, e.g. 'go.mod.tpl' rendered with
+	// the module path and the Dependencies.
+	Templates fs.FS
+	// ControllerGen configures the controller-gen run.
+	ControllerGen ControllerGen
 }
 
 type Result struct {
-	WorkDir  string
+	WorkDir string
+	// Manifest holds all the generated CRDs, in the order
+	// of the kinds, as a multi-document YAML.
 	Manifest []byte
-	Digest   string
-	GVK      schema.GroupVersionKind
+	// Manifests holds every generated CRD keyed by CRD name,
+	// e.g. 'xapps.example.org'.
+	Manifests map[string][]byte
+	// CRDs holds every generated CRD, decoded, keyed by CRD name;
+	// the objects are private copies the caller may change.
+	CRDs map[string]*apiextensionsv1.CustomResourceDefinition
+	// Digest is the SHA-256 of the canonicalized JSON schemas and of all
+	// the options affecting the manifest, crdgen version included.
+	Digest string
+	// GVK of the (first) kind, with the storage version.
+	GVK schema.GroupVersionKind
+	// Violations are the API server rules broken by the CRDs; a CRD
+	// with violations would be rejected when applied.
+	Violations []Violation
+	// CacheHit reports whether the manifest comes from Options.Cache.
+	CacheHit bool
+	// Scaffold is the generated Go module when Options.Scaffold is set.
+	Scaffold fs.FS
 	Err      error
 }
 
 func Generate(ctx context.Context, opts Options) (res Result) {
-	if opts.Verbose {
-		log.SetOutput(os.Stderr)
-	} else {
-		log.SetOutput(io.Discard)
+	if err := opts.Dependencies.validate(); err != nil {
+		res.Err = err
+		return
+	}
+	if err := opts.Toolchain.validate(); err != nil {
+		res.Err = err
+		return
+	}
+	if err := validateBoilerplate(opts.Boilerplate); err != nil {
+		res.Err = fmt.Errorf("invalid boilerplate: %w", err)
+		return
+	}
+	if err := opts.ControllerGen.validate(); err != nil {
+		res.Err = err
+		return
+	}
+
+	if opts.Scaffold != nil {
+		if opts.Native {
+			res.Err = errors.New("a scaffold requires the controller-gen mode")
+			return
+		}
+		if opts.ControllerGen.SkipDeepCopy {
+			res.Err = errors.New("a scaffold requires the DeepCopy methods")
+			return
+		}
+
+		res.Err = opts.Scaffold.validate()
+		if res.Err != nil {
+			return
+		}
 	}
 
-	spec, err := opts.SpecJsonSchemaGetter.Get()
+	all, err := plans(ctx, opts)
 	if err != nil {
 		res.Err = err
 		return
 	}
 
-	res.GVK = opts.GVK
+	res.GVK = all[0].gvk()
 
-	nfo := coder.Resource{
-		Group:      opts.GVK.Group,
-		Version:    opts.GVK.Version,
-		Kind:       opts.GVK.Kind,
-		SpecSchema: spec,
-		Categories: opts.Categories,
-		Managed:    opts.Managed,
+	res.Err = checkSchemas(all)
+	if res.Err != nil {
+		return
 	}
 
-	if opts.StatusJsonSchemaGetter != nil {
-		nfo.StatusSchema, err = opts.StatusJsonSchemaGetter.Get()
-		if err != nil {
-			res.Err = err
+	res.Digest, res.Err = digest(opts, all)
+	if res.Err != nil {
+		return
+	}
+
+	if opts.Cache != nil && opts.Scaffold == nil {
+		res.Manifest, res.CacheHit = opts.Cache.Get(res.Digest)
+		if res.CacheHit {
+			res.Manifests, res.Err = splitManifests(res.Manifest)
+			if res.Err != nil {
+				return
+			}
+
+			if opts.Provenance != nil {
+				res.Manifest, res.Err = stamp(res.Manifests, all, opts.Provenance, res.Digest)
+				if res.Err != nil {
+					return
+				}
+			}
+
+			res.CRDs, res.Err = decodeManifests(res.Manifests)
+			if res.Err != nil {
+				return
+			}
+
+			res.Violations, res.Err = validateAll(ctx, all, res.CRDs)
 			return
 		}
 	}
 
-	cfg, err := defaultCodeGeneratorOptions(opts.WorkDir)
-	if err != nil {
-		res.Err = err
+	var manifests map[string][]byte
+	var module fstest.MapFS
+	if opts.Native {
+		manifests, res.Err = emitNative(ctx, all)
+	} else {
+		res.WorkDir, manifests, module, res.Err = emitControllerGen(ctx, all, opts)
+	}
+	if res.Err != nil {
 		return
 	}
-	res.WorkDir = cfg.Workdir
 
-	clean := len(os.Getenv("CRDGEN_CLEAN_WORKDIR")) == 0
-	if clean {
-		defer os.RemoveAll(cfg.Workdir)
+	res.Manifests = make(map[string][]byte, len(all))
+	for _, p := range all {
+		dat, ok := manifests[p.name]
+		if !ok {
+			res.Err = &ReadManifestError{File: p.name, Err: fs.ErrNotExist}
+			return
+		}
+
+		fns := []patchFunc{}
+		// controller-gen always names the list after the kind
+		if !opts.Native && len(p.kind.ListKind) > 0 {
+			fns = append(fns, withListKind(p.kind.ListKind))
+		}
+		if p.kind.Conversion != nil {
+			fns = append(fns, withConversion(p.kind.Conversion))
+		}
+		if len(p.labels) > 0 {
+			fns = append(fns, withLabels(p.labels))
+		}
+		if len(p.annotations) > 0 {
+			fns = append(fns, withAnnotations(p.annotations))
+		}
+
+		res.Manifests[p.name], res.Err = patch(dat, fns...)
+		if res.Err != nil {
+			return
+		}
+
+		res.Manifest = append(res.Manifest, res.Manifests[p.name]...)
 	}
 
-	if err := coder.Do(&nfo, cfg); err != nil {
-		res.Err = err
-		return
+	// the provenance is stamped on the manifests after caching them
+	cached := res.Manifest
+	if opts.Provenance != nil {
+		res.Manifest, res.Err = stamp(res.Manifests, all, opts.Provenance, res.Digest)
+		if res.Err != nil {
+			return
+		}
 	}
 
-	buf := bytes.Buffer{}
-	err = assets.Render(&buf, "go.mod", map[string]string{
-		"module": cfg.Module,
-	})
-	if err != nil {
-		res.Err = err
+	res.CRDs, res.Err = decodeManifests(res.Manifests)
+	if res.Err != nil {
 		return
 	}
 
-	err = assets.Export(filepath.Join(cfg.Workdir, "go.mod"), buf.Bytes())
-	if err != nil {
-		res.Err = err
+	res.Violations, res.Err = validateAll(ctx, all, res.CRDs)
+	if res.Err != nil {
 		return
 	}
 
-	cmd := exec.Command("go", "mod", "tidy")
-	cmd.Dir = cfg.Workdir
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		if len(out) > 0 {
-			res.Err = fmt.Errorf("%s: performing 'go mod tidy' (workdir: %s, module: %s, gvk: %s/%s,%s)",
-				string(out), cfg.Workdir, cfg.Module, nfo.Group, nfo.Version, nfo.Kind)
+	if opts.Scaffold != nil {
+		res.Scaffold, res.Err = opts.Scaffold.export(module, all, res.Manifests)
+		if res.Err != nil {
 			return
 		}
-		res.Err = fmt.Errorf("%s: performing 'go mod tidy' (workdir: %s, module: %s, gvk: %s/%s,%s)",
-			err.Error(), cfg.Workdir, cfg.Module, nfo.Group, nfo.Version, nfo.Kind)
-		return
 	}
 
-	cmd = exec.Command("go",
-		"run",
-		"--tags",
-		"generate",
-		"sigs.k8s.io/controller-tools/cmd/controller-gen",
-		"object:headerFile=./hack/boilerplate.go.txt",
-		"paths=./...", "crd:crdVersions=v1",
-		"output:artifacts:config=./crds",
-	)
-	cmd.Dir = cfg.Workdir
-	out, err = cmd.CombinedOutput()
+	if opts.Cache != nil {
+		// a failing cache never fails the generation
+		if err := opts.Cache.Put(res.Digest, cached); err != nil {
+			newLogger(opts.Verbose).Printf("[WRN] Caching manifest %s: %v\n", res.Digest, err)
+		}
+	}
+
+	return
+}
+
+// stamp stamps the provenance annotations on the manifests
+// returning them joined in the order of the plans.
+func stamp(manifests map[string][]byte, all []*crdPlan, prov *Provenance, digest string) ([]byte, error) {
+	now := time.Now()
+
+	var res []byte
+	for _, p := range all {
+		dat, err := patch(manifests[p.name], withAnnotations(prov.annotations(p, digest, now)))
+		if err != nil {
+			return nil, err
+		}
+
+		manifests[p.name] = dat
+		res = append(res, dat...)
+	}
+
+	return res, nil
+}
+
+func emitNative(ctx context.Context, all []*crdPlan) (map[string][]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	res := make(map[string][]byte, len(all))
+	var schErr *crd.SchemaError
+	for _, p := range all {
+		obj, err := crd.Build(p.all...)
+		if errors.As(err, &schErr) {
+			ref := SchemaRef{Kind: p.kind.GVK.Kind, Version: schErr.Version, Schema: schErr.Schema}
+			return nil, &TranspileError{SchemaRef: ref, Err: schErr.Err}
+		}
+		if err != nil {
+			return nil, &CodegenError{Err: err}
+		}
+
+		res[p.name], err = crd.Marshal(obj)
+		if err != nil {
+			return nil, &CodegenError{Err: err}
+		}
+	}
+
+	return res, nil
+}
+
+func emitControllerGen(ctx context.Context, all []*crdPlan, opts Options) (workdir string, manifests map[string][]byte, module fstest.MapFS, err error) {
+	cfg, err := defaultCodeGeneratorOptions(opts.WorkDir)
 	if err != nil {
-		if len(out) > 0 {
-			res.Err = fmt.Errorf("%s: performing 'go run --tags generate...' (workdir: %s, module: %s, gvk: %s/%s,%s)",
-				string(out), cfg.Workdir, cfg.Module, nfo.Group, nfo.Version, nfo.Kind)
-			return
+		return "", nil, nil, err
+	}
+	cfg.Boilerplate = opts.Boilerplate
+	cfg.CRDOptions = opts.ControllerGen.crdOptions()
+	cfg.SkipDeepCopy = opts.ControllerGen.SkipDeepCopy
+	if sc := opts.Scaffold; sc != nil {
+		if len(sc.Module) > 0 {
+			cfg.Module = sc.Module
 		}
-		res.Err = fmt.Errorf("%s: performing 'go run --tags generate...' (workdir: %s, module: %s, gvk: %s/%s,%s)",
-			err.Error(), cfg.Workdir, cfg.Module, nfo.Group, nfo.Version, nfo.Kind)
-		return
+		cfg.Layout = sc.Layout
+		if len(sc.Boilerplate) > 0 {
+			cfg.Boilerplate = sc.Boilerplate
+		}
+	}
+	cfg.Logger = newLogger(opts.Verbose)
+	workdir = cfg.Workdir
+
+	clean := len(os.Getenv("CRDGEN_CLEAN_WORKDIR")) == 0
+	defer func() {
+		// cancelled or timed out runs never leave their workdir behind
+		if clean || ctx.Err() != nil || isContextError(err) {
+			os.RemoveAll(cfg.Workdir)
+		}
+	}()
+
+	resources := resourcesOf(all)
+	if err := coder.Do(resources, cfg); err != nil {
+		return workdir, nil, nil, &CodegenError{Workdir: cfg.Workdir, Err: err}
 	}
 
-	fsys := os.DirFS(cfg.Workdir)
-	all, err := fs.ReadDir(fsys, "crds")
+	buf := bytes.Buffer{}
+	err = assets.Render(&buf, opts.Templates, "go.mod", opts.Dependencies.withDefaults().data(cfg.Module))
 	if err != nil {
-		res.Err = err
-		return
+		return workdir, nil, nil, &CodegenError{Workdir: cfg.Workdir, Err: err}
+	}
+
+	gomod := buf.Bytes()
+
+	toolchainError := func(out []byte, timeout time.Duration, err error) ToolchainError {
+		res := ToolchainError{
+			Workdir: cfg.Workdir,
+			Module:  cfg.Module,
+			Output:  out,
+			Timeout: timeout,
+			Err:     err,
+		}
+		if opts.Offline != nil {
+			if missing := missingModules(out); len(missing) > 0 {
+				res.Err = &MissingModulesError{Modules: missing}
+			}
+		}
+		if !isContextError(err) {
+			res.Diagnostics = parseDiagnostics(out, cfg.Workdir, resources)
+		}
+		return res
+	}
+
+	var env []string
+	if opts.Offline != nil {
+		env = opts.Offline.env()
 	}
+	env = opts.Toolchain.environ(env)
+	gobin := opts.Toolchain.command()
 
-	fp, err := fsys.Open(filepath.Join("crds", all[0].Name()))
+	if opts.Offline != nil {
+		gomod, err = opts.Offline.prepare(ctx, cfg.Workdir, gomod, gobin, env)
+		if err != nil {
+			return workdir, nil, nil, &TidyError{toolchainError(nil, 0, err)}
+		}
+	}
+
+	err = assets.Export(filepath.Join(cfg.Workdir, "go.mod"), gomod)
 	if err != nil {
-		res.Err = err
-		return
+		return workdir, nil, nil, &CodegenError{Workdir: cfg.Workdir, Err: err}
 	}
-	defer fp.Close()
 
-	res.Manifest, res.Err = io.ReadAll(fp)
-	if res.Err != nil {
-		return
+	// vendored modules are used as they are
+	if opts.Offline == nil || len(opts.Offline.VendorDir) == 0 {
+		tctx, cancel := withTimeout(ctx, opts.Timeouts.Tidy)
+		out, err := runCommand(tctx, cfg.Workdir, env, gobin, "mod", "tidy")
+		cancel()
+		if err != nil {
+			return workdir, nil, nil, &TidyError{toolchainError(out, opts.Timeouts.Tidy, err)}
+		}
 	}
 
-	h := sha256.New()
-	_, res.Err = h.Write(spec)
-	if len(nfo.StatusSchema) > 0 {
-		_, res.Err = h.Write(nfo.StatusSchema)
-		res.Digest = fmt.Sprintf("%x", h.Sum(nil))
-		return
+	tctx, cancel := withTimeout(ctx, opts.Timeouts.ControllerGen)
+	name, args := opts.ControllerGen.command(gobin, cfg)
+	out, err := runCommand(tctx, cfg.Workdir, env, name, args...)
+	cancel()
+	if err != nil {
+		return workdir, nil, nil, &ControllerGenError{
+			ToolchainError: toolchainError(out, opts.Timeouts.ControllerGen, err),
+			Command:        strings.Join(append([]string{name}, args...), " "),
+		}
 	}
 
-	res.Digest = fmt.Sprintf("%x", h.Sum(nil))
-	return
+	manifests, err = readManifests(os.DirFS(cfg.Workdir), "crds")
+	if err != nil || opts.Scaffold == nil {
+		return workdir, manifests, nil, err
+	}
+
+	module, err = readModule(cfg.Workdir)
+	return workdir, manifests, module, err
 }
 
+// defaultCodeGeneratorOptions creates a private, uniquely named workdir
+// (e.g. '/tmp/github.com/krateoplatformops/xapp-1234567') so that concurrent
+// generations never share their files, even using the same rootDir.
 func defaultCodeGeneratorOptions(rootDir string) (opts coder.Options, err error) {
+	if strings.ContainsAny(rootDir, `/\`) {
+		return opts, fmt.Errorf("invalid workdir '%s': must not contain path separators", rootDir)
+	}
+
 	opts.Module = fmt.Sprintf("github.com/krateoplatformops/%s", rootDir)
-	opts.Workdir = filepath.Join(os.TempDir(), opts.Module)
-	err = os.MkdirAll(opts.Workdir, os.ModePerm)
+
+	base := filepath.Join(os.TempDir(), filepath.Dir(opts.Module))
+	err = os.MkdirAll(base, os.ModePerm)
 	if err != nil {
 		if !errors.Is(err, os.ErrExist) {
 			return opts, err
 		}
 	}
 
-	return opts, nil
+	opts.Workdir, err = os.MkdirTemp(base, rootDir+"-*")
+	return opts, err
+}
+
+// isContextError reports whether err comes from a cancelled or timed out context.
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// newLogger returns a logger private to a single generation,
+// writing to stderr only when verbose.
+func newLogger(verbose bool) *log.Logger {
+	if verbose {
+		return log.New(os.Stderr, "", log.LstdFlags)
+	}
+	return log.New(io.Discard, "", 0)
 }
@@ -4,13 +4,26 @@
 package crdgen_test
 
 import (
+	"archive/tar"
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"github.com/krateoplatformops/crdgen/internal/ptr"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 )
 
@@ -26,8 +39,8 @@ func TestDuplicateStructs(t *testing.T) {
 			Version: "v1alpha1",
 			Kind:    "Xapp",
 		},
-		SpecJsonSchemaGetter: &fileJsonSchemaGetter{"./testdata/duplicate.structs.schema.json"},
-		//StatusJsonSchemaGetter: &fileJsonSchemaGetter{"./testdata/hello.status.schema.json"},
+		SpecJsonSchemaGetter: getter.File("./testdata/duplicate.structs.schema.json"),
+		//StatusJsonSchemaGetter: getter.File("./testdata/hello.status.schema.json"),
 	}
 
 	res := crdgen.Generate(context.TODO(), opts)
@@ -55,8 +68,8 @@ func TestExample(t *testing.T) {
 			Version: "v1alpha1",
 			Kind:    "Xapp",
 		},
-		SpecJsonSchemaGetter: &fileJsonSchemaGetter{"./testdata/issue.43.hack.json"},
-		//StatusJsonSchemaGetter: &fileJsonSchemaGetter{"./testdata/hello.status.schema.json"},
+		SpecJsonSchemaGetter: getter.File("./testdata/issue.43.hack.json"),
+		//StatusJsonSchemaGetter: getter.File("./testdata/hello.status.schema.json"),
 	}
 
 	res := crdgen.Generate(context.TODO(), opts)
@@ -88,8 +101,8 @@ func TestArrayEnums(t *testing.T) {
 			Version: "v1alpha1",
 			Kind:    "Xapp",
 		},
-		SpecJsonSchemaGetter:   &fileJsonSchemaGetter{"./testdata/array.enums.schema.json"},
-		StatusJsonSchemaGetter: &bytesJsonSchemaGetter{data: []byte(preserveUnknownFields)},
+		SpecJsonSchemaGetter:   getter.File("./testdata/array.enums.schema.json"),
+		StatusJsonSchemaGetter: getter.Bytes(preserveUnknownFields),
 	}
 
 	res := crdgen.Generate(context.TODO(), opts)
@@ -106,28 +119,452 @@ func TestArrayEnums(t *testing.T) {
 	fmt.Println(string(res.Manifest))
 }
 
-var _ crdgen.JsonSchemaGetter = (*fileJsonSchemaGetter)(nil)
+func TestVersions(t *testing.T) {
+	opts := crdgen.Options{
+		Managed: true,
+		WorkDir: "xapp",
+		GVK: schema.GroupVersionKind{
+			Group: "example.org",
+			Kind:  "Xapp",
+		},
+		Versions: []crdgen.Version{
+			{
+				Name:                 "v1alpha1",
+				Deprecated:           true,
+				DeprecationWarning:   "example.org/v1alpha1 Xapp is deprecated, use v1beta1",
+				SpecJsonSchemaGetter: getter.File("./testdata/issue.43.hack.json"),
+			},
+			{
+				Name:                 "v1beta1",
+				Storage:              true,
+				SpecJsonSchemaGetter: getter.File("./testdata/duplicate.structs.schema.json"),
+			},
+		},
+		Conversion: &crdgen.ConversionWebhook{
+			Service: apiextensionsv1.ServiceReference{
+				Namespace: "krateo-system",
+				Name:      "xapp-webhook",
+				Path:      ptr.To("/convert"),
+			},
+		},
+	}
+
+	res := crdgen.Generate(context.TODO(), opts)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	if res.GVK.Version != "v1beta1" {
+		t.Errorf("expected storage version v1beta1, got %s", res.GVK.Version)
+	}
+
+	if !strings.Contains(string(res.Manifest), "strategy: Webhook") {
+		t.Errorf("expected webhook conversion strategy")
+	}
 
-type fileJsonSchemaGetter struct {
-	filename string
+	fmt.Println(string(res.Manifest))
 }
 
-func (f *fileJsonSchemaGetter) Get() ([]byte, error) {
-	fin, err := os.Open(f.filename)
-	if err != nil {
-		return nil, err
+func TestClusterScope(t *testing.T) {
+	opts := crdgen.Options{
+		Managed:    true,
+		WorkDir:    "tenant",
+		Plural:     "tenancies",
+		ShortNames: []string{"tn"},
+		ListKind:   "TenantCollection",
+		Scope:      apiextensionsv1.ClusterScoped,
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Tenant",
+		},
+		SpecJsonSchemaGetter: getter.File("./testdata/hello.spec.schema.json"),
+	}
+
+	res := crdgen.Generate(context.TODO(), opts)
+	if res.Err != nil {
+		t.Fatal(res.Err)
 	}
-	defer fin.Close()
 
-	return io.ReadAll(fin)
+	for _, want := range []string{"scope: Cluster", "plural: tenancies", "listKind: TenantCollection"} {
+		if !strings.Contains(string(res.Manifest), want) {
+			t.Errorf("expected %q in manifest", want)
+		}
+	}
+
+	fmt.Println(string(res.Manifest))
 }
 
-var _ crdgen.JsonSchemaGetter = (*bytesJsonSchemaGetter)(nil)
+func TestTidyTimeout(t *testing.T) {
+	t.Setenv("CRDGEN_CLEAN_WORKDIR", "FALSE")
+
+	opts := crdgen.Options{
+		WorkDir: "timeout",
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Xapp",
+		},
+		SpecJsonSchemaGetter: getter.File("./testdata/issue.43.hack.json"),
+		Timeouts: crdgen.Timeouts{
+			Tidy: time.Millisecond,
+		},
+	}
+
+	res := crdgen.Generate(context.TODO(), opts)
+	if !errors.Is(res.Err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline exceeded error, got: %v", res.Err)
+	}
 
-type bytesJsonSchemaGetter struct {
-	data []byte
+	// timed out runs never leave their workdir behind
+	if _, err := os.Stat(res.WorkDir); !os.IsNotExist(err) {
+		t.Fatalf("expected workdir '%s' to be removed", res.WorkDir)
+	}
 }
 
-func (sg *bytesJsonSchemaGetter) Get() ([]byte, error) {
-	return sg.data, nil
+func TestConcurrentWorkDir(t *testing.T) {
+	kinds := []string{"Alpha", "Beta", "Gamma", "Delta"}
+
+	results := make([]crdgen.Result, len(kinds))
+
+	var wg sync.WaitGroup
+	for i, kind := range kinds {
+		wg.Add(1)
+		go func(i int, kind string) {
+			defer wg.Done()
+
+			results[i] = crdgen.Generate(context.TODO(), crdgen.Options{
+				WorkDir: "xapp",
+				GVK: schema.GroupVersionKind{
+					Group:   "example.org",
+					Version: "v1alpha1",
+					Kind:    kind,
+				},
+				SpecJsonSchemaGetter: getter.File("./testdata/issue.43.hack.json"),
+			})
+		}(i, kind)
+	}
+	wg.Wait()
+
+	for i, res := range results {
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+
+		if !strings.Contains(string(res.Manifest), "kind: "+kinds[i]+"\n") {
+			t.Errorf("expected manifest of kind '%s', got:\n%s", kinds[i], res.Manifest)
+		}
+	}
+}
+
+func TestMultiKind(t *testing.T) {
+	spec := getter.Bytes(`{
+		"type": "object",
+		"properties": {
+			"address": {
+				"type": "object",
+				"properties": {"zip": {"type": "string"}}
+			}
+		}
+	}`)
+
+	opts := crdgen.Options{
+		WorkDir: "multi",
+		Kinds: []crdgen.Kind{
+			{
+				GVK: schema.GroupVersionKind{
+					Group: "example.org", Version: "v1alpha1", Kind: "Database",
+				},
+				Managed:              true,
+				SpecJsonSchemaGetter: spec,
+			},
+			{
+				GVK: schema.GroupVersionKind{
+					Group: "example.org", Kind: "Bucket",
+				},
+				Versions: []crdgen.Version{
+					{Name: "v1alpha1", SpecJsonSchemaGetter: spec},
+					{Name: "v1beta1", Storage: true, SpecJsonSchemaGetter: spec},
+				},
+			},
+			{
+				GVK: schema.GroupVersionKind{
+					Group: "storage.example.org", Version: "v1alpha1", Kind: "Volume",
+				},
+				Scope:                apiextensionsv1.ClusterScoped,
+				SpecJsonSchemaGetter: spec,
+			},
+		},
+	}
+
+	res := crdgen.Generate(context.TODO(), opts)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	for _, name := range []string{"databases.example.org", "buckets.example.org", "volumes.storage.example.org"} {
+		if _, ok := res.Manifests[name]; !ok {
+			t.Errorf("missing manifest of '%s'", name)
+		}
+	}
+
+	fmt.Println(string(res.Manifest))
+}
+
+func TestOffline(t *testing.T) {
+	opts := crdgen.Options{
+		WorkDir: "offline",
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Xapp",
+		},
+		Managed:              true,
+		SpecJsonSchemaGetter: getter.File("./testdata/issue.43.hack.json"),
+	}
+
+	// populates the default module cache
+	if res := crdgen.Generate(context.TODO(), opts); res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	opts.Offline = &crdgen.Offline{}
+
+	res := crdgen.Generate(context.TODO(), opts)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	opts.Offline = &crdgen.Offline{ModCacheDir: t.TempDir()}
+
+	res = crdgen.Generate(context.TODO(), opts)
+
+	var missing *crdgen.MissingModulesError
+	if !errors.As(res.Err, &missing) {
+		t.Fatalf("expected a missing modules error, got: %v", res.Err)
+	}
+
+	if len(missing.Modules) != 4 {
+		t.Errorf("expected 4 missing modules, got: %v", missing.Modules)
+	}
+}
+
+func TestOfflineVendor(t *testing.T) {
+	t.Setenv("CRDGEN_CLEAN_WORKDIR", "FALSE")
+
+	opts := crdgen.Options{
+		WorkDir: "vendored",
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Xapp",
+		},
+		Managed:              true,
+		SpecJsonSchemaGetter: getter.File("./testdata/issue.43.hack.json"),
+	}
+
+	res := crdgen.Generate(context.TODO(), opts)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	defer os.RemoveAll(res.WorkDir)
+
+	cmd := exec.Command("go", "mod", "vendor")
+	cmd.Dir = res.WorkDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s: %v", out, err)
+	}
+
+	opts.Offline = &crdgen.Offline{
+		VendorDir: filepath.Join(res.WorkDir, "vendor"),
+	}
+
+	got := crdgen.Generate(context.TODO(), opts)
+	if got.Err != nil {
+		t.Fatal(got.Err)
+	}
+	defer os.RemoveAll(got.WorkDir)
+
+	if string(got.Manifest) != string(res.Manifest) {
+		t.Errorf("expected the same manifest generating offline")
+	}
+
+	opts.Offline = &crdgen.Offline{VendorDir: t.TempDir()}
+	if res := crdgen.Generate(context.TODO(), opts); res.Err == nil {
+		t.Errorf("expected an error for an empty vendor directory")
+	}
+}
+
+func TestScaffold(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "acme")
+
+	opts := crdgen.Options{
+		WorkDir: "scaffold",
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Xapp",
+		},
+		Managed:              true,
+		ListKind:             "XappCollection",
+		SpecJsonSchemaGetter: getter.File("./testdata/issue.43.hack.json"),
+		Scaffold: &crdgen.Scaffold{
+			Dir:         dir,
+			Module:      "example.com/acme/platform",
+			Layout:      "api/{shortGroup}/{version}",
+			Boilerplate: "// Copyright 2025 ACME Inc.",
+		},
+	}
+
+	res := crdgen.Generate(context.TODO(), opts)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	for name, want := range map[string]string{
+		"go.mod":                             "module example.com/acme/platform",
+		"api/example/v1alpha1/xapp_types.go": "type Xapp struct",
+		"api/example/v1alpha1/zz_generated.deepcopy.go": "// Copyright 2025 ACME Inc.",
+		"apis/apis.go":                `"example.com/acme/platform/api/example/v1alpha1"`,
+		"crds/example.org_xapps.yaml": "listKind: XappCollection",
+	} {
+		dat, err := fs.ReadFile(res.Scaffold, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(dat), want) {
+			t.Errorf("expected %q in %s", want, name)
+		}
+	}
+
+	cmd := exec.Command("go", "build", "./...")
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("%s: %v", out, err)
+	}
+
+	buf := bytes.Buffer{}
+	if err := res.WriteScaffold(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for tr := tar.NewReader(&buf); ; {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		found = found || hdr.Name == "go.sum"
+	}
+	if !found {
+		t.Errorf("expected go.sum in the scaffold tar stream")
+	}
+}
+
+func TestControllerGenOptions(t *testing.T) {
+	opts := crdgen.Options{
+		Managed: true,
+		WorkDir: "hello",
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Hello",
+		},
+		SpecJsonSchemaGetter: getter.File("./testdata/hello.spec.schema.json"),
+		ControllerGen: crdgen.ControllerGen{
+			MaxDescLen:   ptr.To(0),
+			SkipDeepCopy: true,
+		},
+	}
+
+	res := crdgen.Generate(context.TODO(), opts)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	if strings.Contains(string(res.Manifest), "description:") {
+		t.Errorf("expected no descriptions with maxDescLen=0")
+	}
+
+	fmt.Println(string(res.Manifest))
+}
+
+func TestFractionalBounds(t *testing.T) {
+	const spec = `{
+		"type": "object",
+		"properties": {
+			"ratio": {"type": "number", "minimum": 0.5, "maximum": 2.5, "multipleOf": 0.25},
+			"replicas": {"type": "integer", "minimum": 0.5, "maximum": 4.5}
+		}
+	}`
+
+	props := func(native bool) map[string]apiextensionsv1.JSONSchemaProps {
+		res := crdgen.Generate(context.TODO(), crdgen.Options{
+			WorkDir: "bounds",
+			GVK: schema.GroupVersionKind{
+				Group:   "example.org",
+				Version: "v1alpha1",
+				Kind:    "Bounds",
+			},
+			Native:               native,
+			SpecJsonSchemaGetter: getter.Bytes(spec),
+			ControllerGen:        crdgen.ControllerGen{AllowDangerousTypes: true},
+		})
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+
+		if len(res.CRDs) != 1 {
+			t.Fatalf("expected 1 CRD, got %d", len(res.CRDs))
+		}
+		for _, crd := range res.CRDs {
+			return crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties
+		}
+		return nil
+	}
+
+	native, gen := props(true), props(false)
+	for _, name := range []string{"ratio", "replicas"} {
+		want, got := native[name], gen[name]
+		if !reflect.DeepEqual(want.Minimum, got.Minimum) || !reflect.DeepEqual(want.Maximum, got.Maximum) ||
+			!reflect.DeepEqual(want.MultipleOf, got.MultipleOf) {
+			t.Errorf("%s: expected the same bounds, native min=%v max=%v multipleOf=%v, controller-gen min=%v max=%v multipleOf=%v",
+				name, ptr.Deref(want.Minimum, 0), ptr.Deref(want.Maximum, 0), ptr.Deref(want.MultipleOf, 0),
+				ptr.Deref(got.Minimum, 0), ptr.Deref(got.Maximum, 0), ptr.Deref(got.MultipleOf, 0))
+		}
+	}
+
+	if got := ptr.Deref(native["ratio"].Minimum, 0); got != 0.5 {
+		t.Errorf("expected the fractional minimum 0.5 kept, got %v", got)
+	}
+	if got := ptr.Deref(native["replicas"].Minimum, 0); got != 1 {
+		t.Errorf("expected the integer minimum rounded up to 1, got %v", got)
+	}
+}
+
+func TestIrregularPlural(t *testing.T) {
+	// the CRD names are immutable: the default plural must stay the
+	// one controller-gen derived before the names were configurable
+	for _, native := range []bool{false, true} {
+		res := crdgen.Generate(context.TODO(), crdgen.Options{
+			WorkDir: "cactus",
+			GVK: schema.GroupVersionKind{
+				Group:   "example.org",
+				Version: "v1alpha1",
+				Kind:    "Cactus",
+			},
+			Native:               native,
+			SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+		})
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+
+		if _, ok := res.CRDs["cacti.example.org"]; !ok {
+			t.Errorf("native %t: expected the CRD 'cacti.example.org', got:\n%s", native, res.Manifest)
+		}
+	}
 }
@@ -0,0 +1,57 @@
+package crdgen
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/krateoplatformops/crdgen/internal/coder"
+)
+
+// diagnosticRE matches the 'file.go:line[:column]: message' lines
+// printed by the Go compiler and by controller-gen.
+var diagnosticRE = regexp.MustCompile(`(?m)^\s*(\S+\.go):(\d+)(?::(\d+))?:\s*(.+?)\s*$`)
+
+// parseDiagnostics extracts the diagnostics from the output of
+// a go command run in workdir, locating the schema property of
+// the generated declarations when possible.
+func parseDiagnostics(out []byte, workdir string, all []*coder.Resource) []Diagnostic {
+	res := []Diagnostic{}
+	seen := map[string]bool{}
+
+	for _, m := range diagnosticRE.FindAllSubmatch(out, -1) {
+		d := Diagnostic{
+			File:    string(m[1]),
+			Message: string(m[4]),
+		}
+		d.Line, _ = strconv.Atoi(string(m[2]))
+		d.Column, _ = strconv.Atoi(string(m[3]))
+
+		if !filepath.IsAbs(d.File) {
+			d.File = filepath.ToSlash(filepath.Clean(d.File))
+		} else if rel, err := filepath.Rel(workdir, d.File); err == nil && !strings.HasPrefix(rel, "..") {
+			d.File = filepath.ToSlash(rel)
+		}
+
+		// controller-gen may report the same error more than once
+		key := d.String()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if loc, ok := coder.Locate(all, workdir, d.File, d.Line); ok {
+			d.Schema = &SchemaRef{
+				Kind:    loc.Resource.Kind,
+				Version: loc.Resource.Version,
+				Schema:  strings.SplitN(loc.Property, ".", 2)[0],
+			}
+			d.Property = loc.Property
+		}
+
+		res = append(res, d)
+	}
+
+	return res
+}
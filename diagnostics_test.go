@@ -0,0 +1,94 @@
+package crdgen
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen/internal/coder"
+)
+
+func TestParseDiagnostics(t *testing.T) {
+	workdir := t.TempDir()
+
+	res := &coder.Resource{
+		Group:   "example.org",
+		Version: "v1alpha1",
+		Kind:    "Xapp",
+		SpecSchema: []byte(`{
+			"type": "object",
+			"properties": {
+				"address": {
+					"type": "object",
+					"properties": {"zip": {"type": "string"}}
+				},
+				"tags": {
+					"type": "array",
+					"items": {"type": "string"}
+				}
+			}
+		}`),
+		Served:  true,
+		Storage: true,
+	}
+
+	all := []*coder.Resource{res}
+	err := coder.Do(all, coder.Options{Module: "github.com/krateoplatformops/xapp", Workdir: workdir})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := "apis/example.org/v1alpha1/xapp_types.go"
+	zip := lineOf(t, filepath.Join(workdir, file), "Zip ")
+	tags := lineOf(t, filepath.Join(workdir, file), "Tags ")
+
+	out := fmt.Sprintf(`# github.com/krateoplatformops/xapp/apis/example.org/v1alpha1
+%s:%d:2: undefined: Foo
+%s:%d: invalid field type
+%s:%d: invalid field type
+go: some error without position
+`, file, zip, filepath.Join(workdir, file), tags, filepath.Join(workdir, file), tags)
+
+	got := parseDiagnostics([]byte(out), workdir, all)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 diagnostics, got: %v", got)
+	}
+
+	if got[0].Line != zip || got[0].Column != 2 || got[0].Message != "undefined: Foo" {
+		t.Errorf("unexpected diagnostic: %v", got[0])
+	}
+	if got[0].Property != "spec.address.zip" || got[0].Schema == nil || got[0].Schema.Kind != "Xapp" {
+		t.Errorf("expected property 'spec.address.zip' of Xapp, got: %v", got[0])
+	}
+
+	if got[1].File != file {
+		t.Errorf("expected file relative to the workdir, got: %s", got[1].File)
+	}
+	if got[1].Property != "spec.tags" {
+		t.Errorf("expected property 'spec.tags', got: %s", got[1].Property)
+	}
+}
+
+// lineOf returns the number of the first line of the file holding the text.
+func lineOf(t *testing.T, filename, text string) int {
+	t.Helper()
+
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for n := 1; sc.Scan(); n++ {
+		if strings.Contains(sc.Text(), text) {
+			return n
+		}
+	}
+
+	t.Fatalf("'%s' not found in %s", text, filename)
+	return 0
+}
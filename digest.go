@@ -0,0 +1,180 @@
+package crdgen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+const modulePath = "github.com/krateoplatformops/crdgen"
+
+// CanonicalJSON returns the canonical form of a JSON document: no
+// insignificant whitespace, object keys sorted and numbers preserved
+// verbatim; documents that differ only in layout share the same form.
+func CanonicalJSON(data []byte) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var val any
+	if err := dec.Decode(&val); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after the JSON document")
+	}
+
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(val); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// digestVersion holds the fields of a version affecting the output.
+type digestVersion struct {
+	Name               string          `json:"name"`
+	Served             bool            `json:"served"`
+	Storage            bool            `json:"storage"`
+	Deprecated         bool            `json:"deprecated"`
+	DeprecationWarning string          `json:"deprecationWarning,omitempty"`
+	Spec               json.RawMessage `json:"spec"`
+	Status             json.RawMessage `json:"status,omitempty"`
+}
+
+// digestInput holds everything affecting the generated manifests.
+type digestInput struct {
+	CrdgenVersion string        `json:"crdgenVersion"`
+	Native        bool          `json:"native"`
+	Dependencies  *Dependencies `json:"dependencies,omitempty"`
+	// Templates holds the SHA-256 of the overridden templates.
+	Templates map[string]string `json:"templates,omitempty"`
+	// CRDOptions are the options of the controller-gen CRD generator.
+	CRDOptions []string     `json:"crdOptions,omitempty"`
+	Kinds      []digestKind `json:"kinds"`
+}
+
+// digestKind holds the fields of a kind affecting the output.
+type digestKind struct {
+	Group       string             `json:"group"`
+	Kind        string             `json:"kind"`
+	Scope       string             `json:"scope"`
+	Plural      string             `json:"plural"`
+	Singular    string             `json:"singular"`
+	ShortNames  []string           `json:"shortNames,omitempty"`
+	ListKind    string             `json:"listKind"`
+	Categories  []string           `json:"categories,omitempty"`
+	Managed     bool               `json:"managed"`
+	Conversion  *ConversionWebhook `json:"conversion,omitempty"`
+	Labels      map[string]string  `json:"labels,omitempty"`
+	Annotations map[string]string  `json:"annotations,omitempty"`
+	Versions    []digestVersion    `json:"versions"`
+}
+
+// digest returns the SHA-256 of the canonicalized schemas and
+// of all the options affecting the generated manifests.
+func digest(opts Options, all []*crdPlan) (string, error) {
+	in := digestInput{
+		CrdgenVersion: moduleVersion(),
+		Native:        opts.Native,
+	}
+
+	// the Go module affects the manifests in controller-gen mode only
+	if !opts.Native {
+		deps := opts.Dependencies.withDefaults()
+		in.Dependencies = &deps
+		in.CRDOptions = opts.ControllerGen.crdOptions()
+
+		var err error
+		in.Templates, err = overriddenTemplates(opts.Templates)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, p := range all {
+		el, err := digestKindOf(p)
+		if err != nil {
+			return "", err
+		}
+		in.Kinds = append(in.Kinds, el)
+	}
+
+	dat, err := json.Marshal(in)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(dat)), nil
+}
+
+func digestKindOf(p *crdPlan) (res digestKind, err error) {
+	for i, el := range p.all {
+		if i == 0 {
+			res.Group = el.Group
+			res.Kind = el.Kind
+			res.Scope = el.Scope
+			res.Plural = el.PluralName()
+			res.Singular = el.SingularName()
+			res.ShortNames = el.ShortNames
+			res.ListKind = el.ListKindName()
+			res.Categories = el.Categories
+			res.Managed = el.Managed
+			res.Conversion = p.kind.Conversion
+			res.Labels = p.labels
+			res.Annotations = p.annotations
+		}
+
+		ver := digestVersion{
+			Name:               el.Version,
+			Served:             el.Served,
+			Storage:            el.Storage,
+			Deprecated:         el.Deprecated,
+			DeprecationWarning: el.DeprecationWarning,
+		}
+
+		ver.Spec, err = CanonicalJSON(el.SpecSchema)
+		if err != nil {
+			return res, fmt.Errorf("canonicalizing spec JSON schema of version '%s': %w", el.Version, err)
+		}
+
+		if len(el.StatusSchema) > 0 {
+			ver.Status, err = CanonicalJSON(el.StatusSchema)
+			if err != nil {
+				return res, fmt.Errorf("canonicalizing status JSON schema of version '%s': %w", el.Version, err)
+			}
+		}
+
+		res.Versions = append(res.Versions, ver)
+	}
+
+	return res, nil
+}
+
+var moduleVersion = sync.OnceValue(func() string {
+	nfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+
+	if nfo.Main.Path == modulePath {
+		return nfo.Main.Version
+	}
+
+	for _, dep := range nfo.Deps {
+		if dep.Path != modulePath {
+			continue
+		}
+		if dep.Replace != nil {
+			return dep.Replace.Version
+		}
+		return dep.Version
+	}
+
+	return "(devel)"
+})
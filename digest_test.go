@@ -0,0 +1,122 @@
+package crdgen_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestCanonicalJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			input:    `{ "type": "object",  "properties": {"b": {"type": "string"}, "a": {"type": "integer"}} }`,
+			expected: `{"properties":{"a":{"type":"integer"},"b":{"type":"string"}},"type":"object"}`,
+		},
+		{
+			input:    "{\n\t\"maximum\": 1.50,\n\t\"default\": 12345678901234567890\n}",
+			expected: `{"default":12345678901234567890,"maximum":1.50}`,
+		},
+		{
+			input:    `{"pattern": "^<a>&$"}`,
+			expected: `{"pattern":"^<a>&$"}`,
+		},
+	}
+
+	for _, tc := range tests {
+		got, err := crdgen.CanonicalJSON([]byte(tc.input))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(got) != tc.expected {
+			t.Errorf("expected %s, got %s", tc.expected, got)
+		}
+	}
+
+	for _, el := range []string{`{"type": `, `{} {}`, ``} {
+		if _, err := crdgen.CanonicalJSON([]byte(el)); err == nil {
+			t.Errorf("expected an error for %q", el)
+		}
+	}
+}
+
+func TestDigest(t *testing.T) {
+	base := func() crdgen.Options {
+		return crdgen.Options{
+			GVK: schema.GroupVersionKind{
+				Group:   "example.org",
+				Version: "v1alpha1",
+				Kind:    "Xapp",
+			},
+			Native: true,
+			SpecJsonSchemaGetter: getter.Bytes(`{"type": "object",
+				"properties": {"name": {"type": "string"}, "replicas": {"type": "integer"}}}`),
+		}
+	}
+
+	digest := func(opts crdgen.Options) string {
+		res := crdgen.Generate(context.Background(), opts)
+		if res.Err != nil {
+			t.Fatal(res.Err)
+		}
+		return res.Digest
+	}
+
+	want := digest(base())
+
+	same := map[string]func(*crdgen.Options){
+		"layout": func(o *crdgen.Options) {
+			o.SpecJsonSchemaGetter = getter.Bytes(`{
+				"properties": {
+					"replicas": {"type": "integer"},
+					"name": {"type": "string"}
+				},
+				"type": "object"
+			}`)
+		},
+		"default plural": func(o *crdgen.Options) { o.Plural = "xapps" },
+		"default scope":  func(o *crdgen.Options) { o.Scope = "Namespaced" },
+		"provenance":     func(o *crdgen.Options) { o.Provenance = &crdgen.Provenance{} },
+	}
+
+	for name, fn := range same {
+		opts := base()
+		fn(&opts)
+		if got := digest(opts); got != want {
+			t.Errorf("%s: expected the digest not to change", name)
+		}
+	}
+
+	changed := map[string]func(*crdgen.Options){
+		"schema":     func(o *crdgen.Options) { o.SpecJsonSchemaGetter = getter.Bytes(`{"type": "object"}`) },
+		"status":     func(o *crdgen.Options) { o.StatusJsonSchemaGetter = getter.Bytes(`{"type": "object"}`) },
+		"group":      func(o *crdgen.Options) { o.GVK.Group = "example.com" },
+		"version":    func(o *crdgen.Options) { o.GVK.Version = "v1" },
+		"kind":       func(o *crdgen.Options) { o.GVK.Kind = "Yapp" },
+		"scope":      func(o *crdgen.Options) { o.Scope = "Cluster" },
+		"plural":     func(o *crdgen.Options) { o.Plural = "xapplications" },
+		"singular":   func(o *crdgen.Options) { o.Singular = "xapplication" },
+		"shortNames": func(o *crdgen.Options) { o.ShortNames = []string{"xa"} },
+		"listKind":   func(o *crdgen.Options) { o.ListKind = "XappCollection" },
+		"categories": func(o *crdgen.Options) { o.Categories = []string{"krateo"} },
+		"managed":    func(o *crdgen.Options) { o.Managed = true },
+		"labels":     func(o *crdgen.Options) { o.Labels = map[string]string{"team": "platform"} },
+		"annotations": func(o *crdgen.Options) {
+			o.Annotations = map[string]string{"krateo.io/owner": "platform"}
+		},
+	}
+
+	for name, fn := range changed {
+		opts := base()
+		fn(&opts)
+		if got := digest(opts); got == want {
+			t.Errorf("%s: expected the digest to change", name)
+		}
+	}
+}
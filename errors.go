@@ -0,0 +1,231 @@
+package crdgen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/krateoplatformops/crdgen/internal/transpiler"
+	"github.com/krateoplatformops/crdgen/internal/transpiler/jsonschema"
+)
+
+var (
+	// ErrInvalidSchema is matched by the errors due to a JSON
+	// schema that cannot be turned into a CRD.
+	ErrInvalidSchema = errors.New("invalid JSON schema")
+	// ErrToolchain is matched by the errors of the Go toolchain
+	// and of controller-gen.
+	ErrToolchain = errors.New("toolchain failure")
+)
+
+// SchemaRef identifies one of the JSON schemas of a generation.
+type SchemaRef struct {
+	Kind    string
+	Version string
+	// Schema is either "spec" or "status".
+	Schema string
+}
+
+func (r SchemaRef) String() string {
+	return fmt.Sprintf("%s JSON schema of %s, version '%s'", r.Schema, r.Kind, r.Version)
+}
+
+// FetchError reports the failure of a JSON schema getter.
+type FetchError struct {
+	SchemaRef
+	Err error
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("fetching %s: %v", e.SchemaRef, e.Err)
+}
+
+func (e *FetchError) Unwrap() error { return e.Err }
+
+// ParseError reports a JSON schema that is not valid JSON
+// or that does not comply with the JSON schema format.
+type ParseError struct {
+	SchemaRef
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parsing %s: %v", e.SchemaRef, e.Err)
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+func (e *ParseError) Is(target error) bool { return target == ErrInvalidSchema }
+
+// TranspileError reports a JSON schema that cannot
+// be expressed with Go types, e.g. with multiple types.
+type TranspileError struct {
+	SchemaRef
+	Err error
+}
+
+func (e *TranspileError) Error() string {
+	return fmt.Sprintf("transpiling %s: %v", e.SchemaRef, e.Err)
+}
+
+func (e *TranspileError) Unwrap() error { return e.Err }
+
+func (e *TranspileError) Is(target error) bool { return target == ErrInvalidSchema }
+
+// CodegenError reports a failure generating the Go sources
+// or, in native mode, building the CRD.
+type CodegenError struct {
+	Workdir string
+	Err     error
+}
+
+func (e *CodegenError) Error() string {
+	if len(e.Workdir) == 0 {
+		return fmt.Sprintf("building CRD: %v", e.Err)
+	}
+	return fmt.Sprintf("generating Go sources (workdir: %s): %v", e.Workdir, e.Err)
+}
+
+func (e *CodegenError) Unwrap() error { return e.Err }
+
+// Diagnostic is a message of the Go compiler or of controller-gen
+// about a generated file, e.g. 'apis/example.org/v1alpha1/xapp_types.go'.
+type Diagnostic struct {
+	// File is relative to the workdir when generated there.
+	File    string
+	Line    int
+	Column  int
+	Message string
+	// Schema and Property locate, when known, the schema property
+	// the Go declaration comes from, e.g. 'spec.address.zip'.
+	Schema   *SchemaRef
+	Property string
+}
+
+func (d Diagnostic) String() string {
+	pos := fmt.Sprintf("%s:%d", d.File, d.Line)
+	if d.Column > 0 {
+		pos = fmt.Sprintf("%s:%d", pos, d.Column)
+	}
+
+	if d.Schema == nil {
+		return fmt.Sprintf("%s: %s", pos, d.Message)
+	}
+	return fmt.Sprintf("%s: %s (property '%s' of %s)", pos, d.Message, d.Property, d.Schema)
+}
+
+// ToolchainError holds the outcome of a failed go command.
+type ToolchainError struct {
+	Workdir string
+	Module  string
+	// Output is the combined output of the command.
+	Output []byte
+	// Diagnostics are parsed from Output.
+	Diagnostics []Diagnostic
+	// Timeout of the stage, if any.
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *ToolchainError) message(stage string) string {
+	if isContextError(e.Err) {
+		return stageError(stage, e.Timeout, e.Err).Error()
+	}
+
+	msg := fmt.Sprintf("%s (workdir: %s, module: %s)", stage, e.Workdir, e.Module)
+	switch {
+	case len(e.Diagnostics) > 0:
+		all := make([]string, 0, len(e.Diagnostics))
+		for _, d := range e.Diagnostics {
+			all = append(all, d.String())
+		}
+		return fmt.Sprintf("%s: %s", msg, strings.Join(all, "; "))
+	case len(bytes.TrimSpace(e.Output)) > 0:
+		return fmt.Sprintf("%s: %s: %v", msg, bytes.TrimSpace(e.Output), e.Err)
+	default:
+		return fmt.Sprintf("%s: %v", msg, e.Err)
+	}
+}
+
+// Is matches ErrToolchain unless the stage has been cancelled or timed out.
+func (e *ToolchainError) Is(target error) bool {
+	return target == ErrToolchain && !isContextError(e.Err)
+}
+
+// TidyError reports the failure of the resolution of
+// the dependencies of the generated module ('go mod tidy').
+type TidyError struct {
+	ToolchainError
+}
+
+func (e *TidyError) Error() string { return e.message("performing 'go mod tidy'") }
+
+func (e *TidyError) Unwrap() error { return e.Err }
+
+// ControllerGenError reports the failure of controller-gen, including
+// the compilation errors of the generated module.
+type ControllerGenError struct {
+	ToolchainError
+	// Command is the command line run, either the prebuilt binary
+	// or 'go run', e.g. '/usr/local/bin/controller-gen paths=./...'.
+	Command string
+}
+
+func (e *ControllerGenError) Error() string {
+	return e.message(fmt.Sprintf("performing '%s'", e.Command))
+}
+
+func (e *ControllerGenError) Unwrap() error { return e.Err }
+
+// ReadManifestError reports a generated manifest that
+// cannot be read or does not hold the expected CRD.
+type ReadManifestError struct {
+	// File is the manifest file or, when missing, the CRD name.
+	File string
+	Err  error
+}
+
+func (e *ReadManifestError) Error() string {
+	return fmt.Sprintf("reading manifest '%s': %v", e.File, e.Err)
+}
+
+func (e *ReadManifestError) Unwrap() error { return e.Err }
+
+// checkSchemas parses and transpiles the JSON schemas
+// of all the resources, reporting the first invalid one.
+func checkSchemas(all []*crdPlan) error {
+	for _, p := range all {
+		for _, res := range p.all {
+			ref := SchemaRef{Kind: res.Kind, Version: res.Version, Schema: "spec"}
+			if err := checkSchema(ref, res.SpecSchema); err != nil {
+				return err
+			}
+
+			if len(res.StatusSchema) == 0 {
+				continue
+			}
+
+			ref.Schema = "status"
+			if err := checkSchema(ref, res.StatusSchema); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func checkSchema(ref SchemaRef, data []byte) error {
+	schema, err := jsonschema.ParseReader(bytes.NewReader(data))
+	if err != nil {
+		return &ParseError{SchemaRef: ref, Err: err}
+	}
+
+	if _, err = transpiler.Transpile(schema); err != nil {
+		return &TranspileError{SchemaRef: ref, Err: err}
+	}
+
+	return nil
+}
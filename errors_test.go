@@ -0,0 +1,105 @@
+package crdgen_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestStageErrors(t *testing.T) {
+	gvk := schema.GroupVersionKind{
+		Group:   "demo.example.org",
+		Version: "v1alpha1",
+		Kind:    "Demo",
+	}
+
+	t.Run("fetch", func(t *testing.T) {
+		res := crdgen.Generate(context.TODO(), crdgen.Options{
+			GVK:                    gvk,
+			Native:                 true,
+			SpecJsonSchemaGetter:   getter.Bytes(`{"type": "object"}`),
+			StatusJsonSchemaGetter: getter.File("./testdata/missing.json"),
+		})
+
+		var err *crdgen.FetchError
+		if !errors.As(res.Err, &err) {
+			t.Fatalf("expected a fetch error, got: %v", res.Err)
+		}
+		if err.Kind != "Demo" || err.Version != "v1alpha1" || err.Schema != "status" {
+			t.Errorf("unexpected schema reference: %+v", err.SchemaRef)
+		}
+		if errors.Is(res.Err, crdgen.ErrInvalidSchema) {
+			t.Errorf("fetch errors must not match ErrInvalidSchema")
+		}
+	})
+
+	t.Run("parse", func(t *testing.T) {
+		res := crdgen.Generate(context.TODO(), crdgen.Options{
+			GVK:                  gvk,
+			Native:               true,
+			SpecJsonSchemaGetter: getter.Bytes(`{"type": "object",`),
+		})
+
+		var err *crdgen.ParseError
+		if !errors.As(res.Err, &err) {
+			t.Fatalf("expected a parse error, got: %v", res.Err)
+		}
+		if !errors.Is(res.Err, crdgen.ErrInvalidSchema) {
+			t.Errorf("expected parse errors to match ErrInvalidSchema")
+		}
+	})
+
+	t.Run("transpile", func(t *testing.T) {
+		res := crdgen.Generate(context.TODO(), crdgen.Options{
+			GVK:    gvk,
+			Native: true,
+			SpecJsonSchemaGetter: getter.Bytes(`{
+				"type": "object",
+				"properties": {"port": {"type": ["string", "integer"]}}
+			}`),
+		})
+
+		var err *crdgen.TranspileError
+		if !errors.As(res.Err, &err) {
+			t.Fatalf("expected a transpile error, got: %v", res.Err)
+		}
+		if err.Schema != "spec" {
+			t.Errorf("expected the spec schema, got: %s", err.Schema)
+		}
+		if !errors.Is(res.Err, crdgen.ErrInvalidSchema) {
+			t.Errorf("expected transpile errors to match ErrInvalidSchema")
+		}
+	})
+
+	t.Run("recursive type", func(t *testing.T) {
+		res := crdgen.Generate(context.TODO(), crdgen.Options{
+			GVK:    gvk,
+			Native: true,
+			SpecJsonSchemaGetter: getter.Bytes(`{
+				"type": "object",
+				"properties": {"root": {"$ref": "#/definitions/node"}},
+				"definitions": {
+					"node": {
+						"type": "object",
+						"properties": {"child": {"$ref": "#/definitions/node"}}
+					}
+				}
+			}`),
+		})
+
+		var err *crdgen.TranspileError
+		if !errors.As(res.Err, &err) {
+			t.Fatalf("expected a transpile error, got: %v", res.Err)
+		}
+		if err.Schema != "spec" || err.Version != gvk.Version {
+			t.Errorf("expected the %s spec schema, got: %s %s", gvk.Version, err.Version, err.Schema)
+		}
+		if !errors.Is(res.Err, crdgen.ErrInvalidSchema) {
+			t.Errorf("expected schema errors to match ErrInvalidSchema")
+		}
+	})
+}
@@ -0,0 +1,61 @@
+// Package getter provides the JSON schema getters used to feed
+// crdgen.Generate: from files, from bytes and from Helm charts.
+package getter
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// File gets the JSON schema from a file; YAML files
+// (with .yaml or .yml extension) are converted to JSON.
+type File string
+
+func (f File) Get(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	dat, err := os.ReadFile(string(f))
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(string(f))) {
+	case ".yaml", ".yml":
+		return yaml.YAMLToJSON(dat)
+	}
+
+	return dat, nil
+}
+
+// Source returns the path of the file.
+func (f File) Source() string {
+	return string(f)
+}
+
+// Bytes gets the JSON schema from memory; YAML
+// documents are converted to JSON.
+type Bytes []byte
+
+func (b Bytes) Get(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if isJSON(b) {
+		return bytes.Clone(b), nil
+	}
+
+	return yaml.YAMLToJSON(b)
+}
+
+// isJSON reports whether the document looks like a JSON object.
+func isJSON(dat []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(dat), []byte("{"))
+}
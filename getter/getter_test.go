@@ -0,0 +1,228 @@
+package getter_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen/getter"
+)
+
+func TestFile(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"schema.json": `{"type": "object", "properties": {"name": {"type": "string"}}}`,
+		"schema.yaml": "type: object\nproperties:\n  name:\n    type: string\n",
+	}
+
+	for name, content := range files {
+		writeFile(t, filepath.Join(dir, name), content)
+
+		dat, err := getter.File(filepath.Join(dir, name)).Get(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertJSON(t, dat, `{"type": "object", "properties": {"name": {"type": "string"}}}`)
+	}
+
+	if _, err := getter.File(filepath.Join(dir, "missing.json")).Get(context.Background()); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+
+	if got := getter.File("schema.json").Source(); got != "schema.json" {
+		t.Errorf("expected source 'schema.json', got '%s'", got)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	dat, err := getter.Bytes(`{"type": "object"}`).Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(dat) != `{"type": "object"}` {
+		t.Errorf("expected the JSON schema unchanged, got %s", dat)
+	}
+
+	dat, err = getter.Bytes("type: object\n").Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSON(t, dat, `{"type": "object"}`)
+}
+
+func TestHelm(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "app")
+
+	writeFile(t, filepath.Join(root, "Chart.yaml"), `apiVersion: v2
+name: app
+version: 1.2.3
+dependencies:
+  - name: postgresql
+    alias: db
+  - name: postgresql
+    alias: cache
+  - name: redis
+`)
+	writeFile(t, filepath.Join(root, "values.schema.json"), `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "pattern": "^<[a-z]+>$"},
+		"db": {
+			"type": "object",
+			"properties": {
+				"replicas": {"type": "integer", "default": 3}
+			}
+		}
+	}
+}`)
+
+	pg := filepath.Join(dir, "postgresql")
+	writeFile(t, filepath.Join(pg, "Chart.yaml"), "apiVersion: v2\nname: postgresql\nversion: 0.1.0\n")
+	writeFile(t, filepath.Join(pg, "values.schema.json"), `{
+	"type": "object",
+	"properties": {
+		"replicas": {"type": "integer", "default": 1, "minimum": 1},
+		"storage": {"type": "string"}
+	}
+}`)
+	writeFile(t, filepath.Join(pg, "charts", "metrics", "Chart.yaml"), "apiVersion: v2\nname: metrics\nversion: 0.1.0\n")
+	writeFile(t, filepath.Join(pg, "charts", "metrics", "values.schema.json"), `{
+	"type": "object",
+	"properties": {"enabled": {"type": "boolean"}}
+}`)
+	writeFile(t, filepath.Join(root, "charts", "postgresql-0.1.0.tgz"), string(archive(t, pg)))
+
+	writeFile(t, filepath.Join(root, "charts", "redis", "Chart.yaml"), "apiVersion: v2\nname: redis\nversion: 0.1.0\n")
+	writeFile(t, filepath.Join(root, "charts", "redis", "values.schema.json"), `{
+	"type": "object",
+	"properties": {"port": {"type": "integer"}}
+}`)
+
+	writeFile(t, filepath.Join(root, "charts", "common", "Chart.yaml"), "apiVersion: v2\nname: common\nversion: 0.1.0\n")
+
+	want := `{
+	"type": "object",
+	"properties": {
+		"name": {"type": "string", "pattern": "^<[a-z]+>$"},
+		"db": {
+			"type": "object",
+			"properties": {
+				"replicas": {"type": "integer", "default": 3, "minimum": 1},
+				"storage": {"type": "string"},
+				"metrics": {"type": "object", "properties": {"enabled": {"type": "boolean"}}}
+			}
+		},
+		"cache": {
+			"type": "object",
+			"properties": {
+				"replicas": {"type": "integer", "default": 1, "minimum": 1},
+				"storage": {"type": "string"},
+				"metrics": {"type": "object", "properties": {"enabled": {"type": "boolean"}}}
+			}
+		},
+		"redis": {"type": "object", "properties": {"port": {"type": "integer"}}}
+	}
+}`
+
+	dat, err := getter.Helm(root).Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSON(t, dat, want)
+
+	tgz := filepath.Join(dir, "app-1.2.3.tgz")
+	writeFile(t, tgz, string(archive(t, root)))
+
+	dat, err = getter.Helm(tgz).Get(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertJSON(t, dat, want)
+
+	if _, err := getter.Helm(filepath.Join(root, "charts", "common")).Get(context.Background()); err == nil {
+		t.Errorf("expected an error for a chart without values.schema.json")
+	}
+}
+
+func assertJSON(t *testing.T, got []byte, want string) {
+	t.Helper()
+
+	var a, b any
+	if err := json.Unmarshal(got, &a); err != nil {
+		t.Fatalf("%s: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &b); err != nil {
+		t.Fatal(err)
+	}
+
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	if !bytes.Equal(x, y) {
+		t.Errorf("expected %s, got %s", y, x)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+
+	if err := os.MkdirAll(filepath.Dir(name), os.ModePerm); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0666); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// archive packages the chart directory like 'helm package' does.
+func archive(t *testing.T, dir string) []byte {
+	t.Helper()
+
+	buf := bytes.Buffer{}
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	err := filepath.Walk(dir, func(name string, fi os.FileInfo, err error) error {
+		if err != nil || fi.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(filepath.Dir(dir), name)
+		if err != nil {
+			return err
+		}
+
+		dat, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		err = tw.WriteHeader(&tar.Header{
+			Name: filepath.ToSlash(rel), Mode: 0644, Size: int64(len(dat)), Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = tw.Write(dat)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
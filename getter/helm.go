@@ -0,0 +1,166 @@
+package getter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/krateoplatformops/crdgen/internal/chart"
+)
+
+// Helm gets the JSON schema from the values.schema.json of a Helm
+// chart, directory or .tgz archive; the schemas of the subcharts are
+// merged in the parent schema under their values key (alias or name).
+type Helm string
+
+func (h Helm) Get(ctx context.Context) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	c, err := chart.Load(string(h))
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := chartSchema(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if res == nil {
+		return nil, fmt.Errorf("chart '%s' has no values.schema.json", c.Metadata.Name)
+	}
+
+	return encode(res)
+}
+
+// Source returns the path of the chart.
+func (h Helm) Source() string {
+	return string(h)
+}
+
+// chartSchema returns the JSON schema of the chart with the schemas
+// of its subcharts merged in; nil when no chart has a schema.
+func chartSchema(c *chart.Chart) (map[string]any, error) {
+	var res map[string]any
+	if len(c.Schema) > 0 {
+		if err := decode(c.Schema, &res); err != nil {
+			return nil, fmt.Errorf("chart '%s': invalid values.schema.json: %w", c.Metadata.Name, err)
+		}
+	}
+
+	for _, sub := range c.Subcharts {
+		schema, err := chartSchema(sub)
+		if err != nil {
+			return nil, err
+		}
+		if schema == nil {
+			continue
+		}
+
+		if res == nil {
+			res = map[string]any{"type": "object"}
+		}
+
+		for _, key := range valuesKeys(c, sub) {
+			mergeProperty(res, key, schema)
+		}
+	}
+
+	return res, nil
+}
+
+// valuesKeys returns the keys of the parent values holding the subchart
+// values: the aliases of the dependency, otherwise the subchart name.
+func valuesKeys(parent, sub *chart.Chart) []string {
+	res := []string{}
+	for _, el := range parent.Metadata.Dependencies {
+		if el.Name == sub.Metadata.Name && len(el.Alias) > 0 {
+			res = append(res, el.Alias)
+		}
+	}
+
+	if len(res) == 0 {
+		res = append(res, sub.Metadata.Name)
+	}
+
+	return res
+}
+
+// mergeProperty sets the subchart schema as the key property of the
+// parent schema; when the parent already defines the property its
+// definitions win over the subchart ones.
+func mergeProperty(parent map[string]any, key string, schema map[string]any) {
+	props, ok := parent["properties"].(map[string]any)
+	if !ok {
+		props = map[string]any{}
+		parent["properties"] = props
+	}
+
+	cur, ok := props[key].(map[string]any)
+	if !ok {
+		props[key] = clone(schema)
+		return
+	}
+
+	props[key] = mergeSchemas(cur, schema)
+}
+
+// mergeSchemas returns dst completed with the definitions of src.
+func mergeSchemas(dst, src map[string]any) map[string]any {
+	res := clone(src)
+	for k, v := range dst {
+		if k != "properties" {
+			res[k] = v
+			continue
+		}
+
+		props, ok := v.(map[string]any)
+		if !ok {
+			res[k] = v
+			continue
+		}
+
+		merged, _ := res[k].(map[string]any)
+		if merged == nil {
+			merged = map[string]any{}
+		}
+		for name, el := range props {
+			a, aok := el.(map[string]any)
+			b, bok := merged[name].(map[string]any)
+			if aok && bok {
+				merged[name] = mergeSchemas(a, b)
+			} else {
+				merged[name] = el
+			}
+		}
+		res[k] = merged
+	}
+
+	return res
+}
+
+func clone(in map[string]any) map[string]any {
+	var res map[string]any
+	dat, _ := encode(in)
+	decode(dat, &res)
+	return res
+}
+
+func encode(v any) ([]byte, error) {
+	buf := bytes.Buffer{}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+func decode(dat []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(dat))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
@@ -2,9 +2,12 @@ package assets
 
 import (
 	"embed"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 	"text/template"
@@ -13,16 +16,52 @@ import (
 //go:embed files/*.tpl
 var tmplFS embed.FS
 
-func Render(w io.Writer, name string, data any) error {
+// Render executes the named template; the templates found in overlay,
+// when not nil, replace the embedded ones with the same file name.
+func Render(w io.Writer, overlay fs.FS, name string, data any) error {
 	if !strings.HasSuffix(name, ".tpl") {
 		name = fmt.Sprintf("%s.tpl", name)
 	}
 
-	eng := template.Must(template.New("").ParseFS(tmplFS, "files/*.tpl"))
+	eng := template.New("").Option("missingkey=error")
+	for _, el := range Names() {
+		dat, err := Read(overlay, el)
+		if err != nil {
+			return err
+		}
 
-	tmpl := template.Must(eng.Clone())
-	tmpl = template.Must(tmpl.ParseFS(tmplFS, "files/"+name))
-	return tmpl.ExecuteTemplate(w, name, data)
+		if _, err := eng.New(el).Parse(string(dat)); err != nil {
+			return fmt.Errorf("parsing template '%s': %w", el, err)
+		}
+	}
+
+	return eng.ExecuteTemplate(w, name, data)
+}
+
+// Names returns the file names of the embedded templates, e.g. 'go.mod.tpl'.
+func Names() []string {
+	files, _ := fs.Glob(tmplFS, "files/*.tpl")
+	res := make([]string, 0, len(files))
+	for _, el := range files {
+		res = append(res, path.Base(el))
+	}
+	return res
+}
+
+// Read returns the content of the named template, read from
+// overlay when it holds a file with the same name.
+func Read(overlay fs.FS, name string) ([]byte, error) {
+	if overlay != nil {
+		dat, err := fs.ReadFile(overlay, name)
+		if err == nil {
+			return dat, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("reading template '%s': %w", name, err)
+		}
+	}
+
+	return tmplFS.ReadFile("files/" + name)
 }
 
 func Export(target string, dat []byte) error {
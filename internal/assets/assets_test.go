@@ -14,10 +14,15 @@ import (
 
 func TestRender(t *testing.T) {
 	ds := map[string]string{
-		"module": "github.com/krateoplatformops/form1",
+		"module":            "github.com/krateoplatformops/form1",
+		"go":                "1.24.0",
+		"providerRuntime":   "v0.9.1",
+		"apimachinery":      "v0.33.0",
+		"controllerRuntime": "v0.20.0",
+		"controllerTools":   "v0.18.0",
 	}
 
-	err := assets.Render(os.Stdout, "go.mod", ds)
+	err := assets.Render(os.Stdout, nil, "go.mod", ds)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -25,11 +30,16 @@ func TestRender(t *testing.T) {
 
 func TestExport(t *testing.T) {
 	ds := map[string]string{
-		"module": "github.com/krateoplatformops/form1",
+		"module":            "github.com/krateoplatformops/form1",
+		"go":                "1.24.0",
+		"providerRuntime":   "v0.9.1",
+		"apimachinery":      "v0.33.0",
+		"controllerRuntime": "v0.20.0",
+		"controllerTools":   "v0.18.0",
 	}
 
 	buf := bytes.Buffer{}
-	err := assets.Render(&buf, "go.mod", ds)
+	err := assets.Render(&buf, nil, "go.mod", ds)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1,10 +1,10 @@
 module {{ .module }}
 
-go 1.24.0
+go {{ .go }}
 
 require (
-	github.com/krateoplatformops/provider-runtime v0.9.1
-	k8s.io/apimachinery v0.33.0
-	sigs.k8s.io/controller-runtime v0.20.0
-	sigs.k8s.io/controller-tools v0.18.0
+	github.com/krateoplatformops/provider-runtime {{ .providerRuntime }}
+	k8s.io/apimachinery {{ .apimachinery }}
+	sigs.k8s.io/controller-runtime {{ .controllerRuntime }}
+	sigs.k8s.io/controller-tools {{ .controllerTools }}
 )
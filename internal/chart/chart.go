@@ -0,0 +1,225 @@
+// Package chart loads the Helm charts metadata and JSON schemas,
+// from a chart directory or a .tgz archive, with their subcharts.
+package chart
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"sigs.k8s.io/yaml"
+)
+
+// maxArchiveSize bounds the uncompressed size of a chart archive.
+const maxArchiveSize = 64 << 20
+
+// Metadata holds the fields of Chart.yaml used by crdgen.
+type Metadata struct {
+	APIVersion   string       `json:"apiVersion"`
+	Name         string       `json:"name"`
+	Version      string       `json:"version"`
+	AppVersion   string       `json:"appVersion,omitempty"`
+	Description  string       `json:"description,omitempty"`
+	Dependencies []Dependency `json:"dependencies,omitempty"`
+}
+
+// Dependency is a chart dependency declared in Chart.yaml
+// (or in requirements.yaml for apiVersion v1 charts).
+type Dependency struct {
+	Name       string `json:"name"`
+	Version    string `json:"version,omitempty"`
+	Repository string `json:"repository,omitempty"`
+	Alias      string `json:"alias,omitempty"`
+	Condition  string `json:"condition,omitempty"`
+}
+
+// Chart is a loaded Helm chart.
+type Chart struct {
+	Metadata Metadata
+	// Schema is the content of values.schema.json, if any.
+	Schema []byte
+	// Subcharts are the charts found in the charts/ directory.
+	Subcharts []*Chart
+}
+
+// Load loads the chart from a directory or a .tgz archive.
+func Load(name string) (*Chart, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if fi.IsDir() {
+		files, err := readDir(name)
+		if err != nil {
+			return nil, err
+		}
+		return load(files)
+	}
+
+	fp, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	return LoadArchive(fp)
+}
+
+// LoadArchive loads the chart from a .tgz archive.
+func LoadArchive(r io.Reader) (*Chart, error) {
+	files, err := readArchive(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return load(files)
+}
+
+// load builds the chart from its files, keyed by slash separated
+// path relative to the chart root.
+func load(files map[string][]byte) (*Chart, error) {
+	dat, ok := files["Chart.yaml"]
+	if !ok {
+		return nil, fmt.Errorf("invalid chart: missing Chart.yaml")
+	}
+
+	res := &Chart{}
+	if err := yaml.Unmarshal(dat, &res.Metadata); err != nil {
+		return nil, fmt.Errorf("invalid Chart.yaml: %w", err)
+	}
+
+	if len(res.Metadata.Name) == 0 {
+		return nil, fmt.Errorf("invalid Chart.yaml: missing chart name")
+	}
+
+	// apiVersion v1 charts declare the dependencies in requirements.yaml
+	if dat, ok := files["requirements.yaml"]; ok && len(res.Metadata.Dependencies) == 0 {
+		req := struct {
+			Dependencies []Dependency `json:"dependencies"`
+		}{}
+		if err := yaml.Unmarshal(dat, &req); err != nil {
+			return nil, fmt.Errorf("invalid requirements.yaml: %w", err)
+		}
+		res.Metadata.Dependencies = req.Dependencies
+	}
+
+	res.Schema = files["values.schema.json"]
+
+	subs := map[string]map[string][]byte{}
+	for key, dat := range files {
+		rel, ok := strings.CutPrefix(key, "charts/")
+		if !ok {
+			continue
+		}
+
+		dir, name, nested := strings.Cut(rel, "/")
+		switch {
+		case nested:
+			if subs[dir] == nil {
+				subs[dir] = map[string][]byte{}
+			}
+			subs[dir][name] = dat
+
+		case strings.HasSuffix(rel, ".tgz"):
+			sub, err := readArchive(bytes.NewReader(dat))
+			if err != nil {
+				return nil, fmt.Errorf("subchart %s: %w", rel, err)
+			}
+			subs[rel] = sub
+		}
+	}
+
+	keys := make([]string, 0, len(subs))
+	for k := range subs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		sub, err := load(subs[k])
+		if err != nil {
+			return nil, fmt.Errorf("subchart %s: %w", k, err)
+		}
+		res.Subcharts = append(res.Subcharts, sub)
+	}
+
+	return res, nil
+}
+
+func readDir(root string) (map[string][]byte, error) {
+	res := map[string][]byte{}
+	err := filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+
+		res[filepath.ToSlash(rel)], err = os.ReadFile(name)
+		return err
+	})
+
+	return res, err
+}
+
+// readArchive reads the files of a chart archive stripping
+// the top level directory, e.g. 'mychart/Chart.yaml'.
+func readArchive(r io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	res := map[string][]byte{}
+
+	size := int64(0)
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(strings.TrimPrefix(hdr.Name, "./"))
+		_, rel, ok := strings.Cut(name, "/")
+		if !ok || strings.HasPrefix(rel, "../") {
+			continue
+		}
+
+		size += hdr.Size
+		if size > maxArchiveSize {
+			return nil, fmt.Errorf("chart archive exceeds %d bytes", maxArchiveSize)
+		}
+
+		res[rel], err = io.ReadAll(io.LimitReader(tr, hdr.Size))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
@@ -1,10 +1,9 @@
 package coder
 
 import (
-	"fmt"
 	"os"
+	"path"
 	"path/filepath"
-	"strings"
 
 	"github.com/dave/jennifer/jen"
 )
@@ -13,19 +12,21 @@ const (
 	pkgApiMachineryRuntime = "k8s.io/apimachinery/pkg/runtime"
 )
 
-func CreateApisDotGo(el *Resource, cfg Options) error {
+func CreateApisDotGo(pkgs []*apiPackage, cfg Options) error {
 	g := jen.NewFile("apis")
 	g.ImportName(pkgApiMachineryRuntime, "runtime")
 
-	alias := fmt.Sprintf("%s%s", strings.ToLower(el.Kind), normalizeVersion(el.Version))
-	pkg := fmt.Sprintf("%s/apis/%s/%s", cfg.Module, strings.ToLower(el.Kind), normalizeVersion(el.Version))
-	g.ImportAlias(pkg, alias)
+	stmts := make([]jen.Code, 0, len(pkgs)+1)
+	stmts = append(stmts, jen.Id("AddToSchemes"))
 
-	g.Line()
+	for _, el := range pkgs {
+		pkg := path.Join(cfg.Module, el.dir())
+		g.ImportAlias(pkg, el.alias())
+
+		stmts = append(stmts, jen.Qual(pkg, "SchemeBuilder").Dot("AddToScheme"))
+	}
 
-	stmts := make([]jen.Code, 2)
-	stmts[0] = jen.Id("AddToSchemes")
-	stmts[1] = generateAddToScheme(el, cfg)
+	g.Line()
 
 	g.Func().Id("init").Params().Block(
 		jen.Id("AddToSchemes").Op("=").Append(stmts...),
@@ -50,9 +51,3 @@ func CreateApisDotGo(el *Resource, cfg Options) error {
 
 	return g.Render(src)
 }
-
-func generateAddToScheme(res *Resource, cfg Options) *jen.Statement {
-	kind := strings.ToLower(res.Kind)
-	pkg := fmt.Sprintf("%s/apis/%s/%s", cfg.Module, kind, normalizeVersion(res.Version))
-	return jen.Qual(pkg, "SchemeBuilder").Dot("AddToScheme")
-}
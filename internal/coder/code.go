@@ -2,53 +2,154 @@ package coder
 
 import (
 	"errors"
+	"fmt"
+	"io"
+	"log"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/krateoplatformops/crdgen/internal/inflect"
+	"github.com/krateoplatformops/crdgen/internal/strutil"
+)
+
+const (
+	ScopeNamespaced = "Namespaced"
+	ScopeCluster    = "Cluster"
 )
 
 type Resource struct {
 	Group        string
 	Version      string
 	Kind         string
+	Scope        string
+	Plural       string
+	Singular     string
+	ShortNames   []string
+	ListKind     string
 	Categories   []string
 	SpecSchema   []byte
 	StatusSchema []byte
 	Managed      bool
+
+	Served             bool
+	Storage            bool
+	Deprecated         bool
+	DeprecationWarning string
+
+	// typePrefix prefixes the nested struct names when
+	// several kinds share the same package.
+	typePrefix string
+	// dir is the package directory, set according to the layout.
+	dir string
+}
+
+// PluralName returns the plural resource name, derived from the kind when not set.
+func (r *Resource) PluralName() string {
+	if len(r.Plural) > 0 {
+		return r.Plural
+	}
+	return inflect.Pluralize(r.Kind)
+}
+
+// SingularName returns the singular resource name, derived from the kind when not set.
+func (r *Resource) SingularName() string {
+	if len(r.Singular) > 0 {
+		return r.Singular
+	}
+	return strings.ToLower(r.Kind)
+}
+
+// ListKindName returns the kind of the list, derived from the kind when not set.
+func (r *Resource) ListKindName() string {
+	if len(r.ListKind) > 0 {
+		return r.ListKind
+	}
+	return fmt.Sprintf("%sList", strutil.ToGolangName(r.Kind))
 }
 
 type Options struct {
 	Module  string
 	Workdir string
+	// Logger receives the debug messages; nil discards them.
+	Logger *log.Logger
+	// Layout of the API packages, DefaultLayout when empty.
+	Layout string
+	// Boilerplate heads the files generated by controller-gen,
+	// DefaultBoilerplate when empty.
+	Boilerplate string
+	// CRDOptions are the options of the controller-gen CRD
+	// generator following 'crdVersions=v1', e.g. 'maxDescLen=0'.
+	CRDOptions []string
+	// SkipDeepCopy skips the controller-gen object generator.
+	SkipDeepCopy bool
 }
 
-func Do(res *Resource, cfg Options) error {
-	err := CreateGenerateDotGo(cfg.Workdir)
-	if err != nil {
-		return err
+// DefaultBoilerplate is the header of the files generated by controller-gen.
+const DefaultBoilerplate = "// Copyright 2024 Krateo SRL."
+
+func (o Options) logger() *log.Logger {
+	if o.Logger == nil {
+		return log.New(io.Discard, "", 0)
 	}
+	return o.Logger
+}
 
-	err = CreateTypesDotGo(cfg.Workdir, res)
+func Do(all []*Resource, cfg Options) error {
+	pkgs, err := groupPackages(all, cfg.Layout)
 	if err != nil {
 		return err
 	}
 
-	err = CreateGroupVersionInfoDotGo(cfg.Workdir, res)
+	err = CreateGenerateDotGo(cfg)
 	if err != nil {
 		return err
 	}
 
-	err = CreateApisDotGo(res, cfg)
-	if err != nil {
-		return err
+	for _, pkg := range pkgs {
+		err = CreateGroupVersionInfoDotGo(cfg.Workdir, pkg.resources)
+		if err != nil {
+			return err
+		}
+
+		if hasFailedObjectRef(pkg.resources) {
+			err = CreateFailedObjectRefDotGo(cfg.Workdir, pkg.resources[0])
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	if res.Managed {
-		err := GenerateManaged(cfg.Workdir, res)
+	for _, res := range all {
+		err = CreateTypesDotGo(cfg.Workdir, res, cfg.logger())
 		if err != nil {
 			return err
 		}
 
-		err = GenerateManagedList(cfg.Workdir, res)
+		if res.Managed {
+			err := GenerateManaged(cfg.Workdir, res)
+			if err != nil {
+				return err
+			}
+
+			err = GenerateManagedList(cfg.Workdir, res)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = CreateApisDotGo(pkgs, cfg)
+	if err != nil {
+		return err
+	}
+
+	for _, versions := range groupKinds(all) {
+		if len(versions) < 2 {
+			continue
+		}
+
+		err = CreateConversionDotGo(versions, cfg)
 		if err != nil {
 			return err
 		}
@@ -74,6 +175,11 @@ func Do(res *Resource, cfg Options) error {
 	}
 	defer fp.Close()
 
-	_, err = fp.WriteString("// Copyright 2024 Krateo SRL.")
+	boilerplate := cfg.Boilerplate
+	if len(boilerplate) == 0 {
+		boilerplate = DefaultBoilerplate
+	}
+
+	_, err = fp.WriteString(boilerplate)
 	return err
 }
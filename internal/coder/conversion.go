@@ -0,0 +1,351 @@
+package coder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/dave/jennifer/jen"
+	"github.com/krateoplatformops/crdgen/internal/strutil"
+	"github.com/krateoplatformops/crdgen/internal/transpiler"
+)
+
+const (
+	pkgConversion = "sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	// maxConversionDepth guards against runaway recursion on nested structs.
+	maxConversionDepth = 32
+)
+
+// CreateConversionDotGo generates the conversion.go file of every version
+// of a kind: the storage version implements conversion.Hub while all the
+// other ones implement conversion.Convertible towards it.
+func CreateConversionDotGo(all []*Resource, cfg Options) error {
+	var hub *Resource
+	for _, el := range all {
+		if el.Storage {
+			hub = el
+			break
+		}
+	}
+	if hub == nil {
+		return fmt.Errorf("no storage version to use as conversion hub")
+	}
+
+	for _, el := range all {
+		if el.Group != hub.Group || el.Kind != hub.Kind {
+			return fmt.Errorf("cannot convert %s, %s into %s, %s",
+				el.Group, el.Kind, hub.Group, hub.Kind)
+		}
+
+		var g *jen.File
+		if el == hub {
+			g = Hub(el)
+		} else {
+			var err error
+			g, err = Convertible(el, hub, cfg)
+			if err != nil {
+				return err
+			}
+		}
+
+		path, err := makeDirs(cfg.Workdir, el.packageDir())
+		if err != nil {
+			return err
+		}
+
+		src, err := os.Create(filepath.Join(path, fileName(el, "conversion.go")))
+		if err != nil {
+			return err
+		}
+
+		err = g.Render(src)
+		src.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Hub marks the resource version as the conversion hub.
+func Hub(res *Resource) *jen.File {
+	kind := strutil.ToGolangName(res.Kind)
+
+	g := jen.NewFile(normalizeVersion(res.Version))
+	g.Comment(fmt.Sprintf("Hub marks this type as a conversion hub: all the other versions of %s", kind))
+	g.Comment("are converted to and from this one.")
+	g.Func().Params(jen.Op("*").Id(kind)).Id("Hub").Params().Block()
+
+	return g
+}
+
+// Convertible generates the ConvertTo and ConvertFrom methods of the resource
+// version: fields having the same path and type on both sides are copied,
+// the others are left as TODO for the developer.
+func Convertible(res, hub *Resource, cfg Options) (*jen.File, error) {
+	kind := strutil.ToGolangName(res.Kind)
+	hubPkg := packagePath(cfg.Module, hub)
+
+	local, err := newConversionModel(res)
+	if err != nil {
+		return nil, fmt.Errorf("version '%s': %w", res.Version, err)
+	}
+
+	remote, err := newConversionModel(hub)
+	if err != nil {
+		return nil, fmt.Errorf("version '%s': %w", hub.Version, err)
+	}
+
+	g := jen.NewFile(normalizeVersion(res.Version))
+	g.ImportName(pkgConversion, "conversion")
+	g.ImportAlias(hubPkg, packageAlias(hub.Group, hub.Version))
+
+	to := &converter{hubPkg: hubPkg, toHub: true, dstVersion: hub.Version}
+	toBody := []jen.Code{
+		jen.Id("dst").Op(":=").Id("dstRaw").Assert(jen.Op("*").Qual(hubPkg, kind)),
+		jen.Line(),
+		jen.Id("dst").Dot("ObjectMeta").Op("=").Id("src").Dot("ObjectMeta"),
+	}
+	toBody = append(toBody, to.convert(remote, local)...)
+	toBody = append(toBody, jen.Line(), jen.Return(jen.Nil()))
+
+	g.Comment(fmt.Sprintf("ConvertTo converts this %s to the Hub version (%s).", kind, hub.Version))
+	g.Func().Params(jen.Id("src").Op("*").Id(kind)).
+		Id("ConvertTo").Params(jen.Id("dstRaw").Qual(pkgConversion, "Hub")).Error().
+		Block(toBody...)
+	g.Line()
+
+	from := &converter{hubPkg: hubPkg, toHub: false, dstVersion: res.Version}
+	fromBody := []jen.Code{
+		jen.Id("src").Op(":=").Id("srcRaw").Assert(jen.Op("*").Qual(hubPkg, kind)),
+		jen.Line(),
+		jen.Id("dst").Dot("ObjectMeta").Op("=").Id("src").Dot("ObjectMeta"),
+	}
+	fromBody = append(fromBody, from.convert(local, remote)...)
+	fromBody = append(fromBody, jen.Line(), jen.Return(jen.Nil()))
+
+	g.Comment(fmt.Sprintf("ConvertFrom converts from the Hub version (%s) to this version.", hub.Version))
+	g.Func().Params(jen.Id("dst").Op("*").Id(kind)).
+		Id("ConvertFrom").Params(jen.Id("srcRaw").Qual(pkgConversion, "Hub")).Error().
+		Block(fromBody...)
+
+	return g, nil
+}
+
+// conversionModel holds the transpiled spec and status of a resource version.
+type conversionModel struct {
+	spec    map[string]transpiler.Struct
+	status  map[string]transpiler.Struct
+	managed bool
+}
+
+func newConversionModel(res *Resource) (*conversionModel, error) {
+	spec, err := res.specStructs()
+	if err != nil {
+		return nil, err
+	}
+
+	mod := &conversionModel{spec: spec, managed: res.Managed}
+
+	if !res.hasStatus() {
+		return mod, nil
+	}
+
+	mod.status, err = res.statusStructs()
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Managed {
+		fields := make(map[string]transpiler.Field)
+		for _, el := range failedObjectRefFields() {
+			fields[el.Name] = el
+		}
+		mod.status["FailedObjectRef"] = transpiler.Struct{
+			Name:   "FailedObjectRef",
+			Fields: fields,
+		}
+
+		mod.status["Root"].Fields["FailedObjectRef"] = transpiler.Field{
+			Name:     "FailedObjectRef",
+			JSONName: "failedObjectRef",
+			Type:     "*FailedObjectRef",
+		}
+	}
+
+	return mod, nil
+}
+
+type converter struct {
+	hubPkg     string
+	toHub      bool
+	dstVersion string
+}
+
+func (c *converter) convert(dst, src *conversionModel) []jen.Code {
+	res := []jen.Code{jen.Line()}
+	res = append(res, c.fields("spec", []string{"Spec"},
+		dst.spec, src.spec, dst.spec["Root"], src.spec["Root"], 0)...)
+
+	switch {
+	case dst.status != nil && src.status != nil:
+		res = append(res, jen.Line())
+		if dst.managed && src.managed {
+			sel := []string{"Status", "ConditionedStatus"}
+			res = append(res, assign(sel, sel))
+		}
+		res = append(res, c.fields("status", []string{"Status"},
+			dst.status, src.status, dst.status["Root"], src.status["Root"], 0)...)
+	case dst.status != nil:
+		res = append(res, jen.Comment(fmt.Sprintf("TODO: status is not available in the source version, fill it for %s", c.dstVersion)))
+	case src.status != nil:
+		res = append(res, jen.Comment(fmt.Sprintf("TODO: status is not available in %s", c.dstVersion)))
+	}
+
+	return res
+}
+
+// fields copies the fields of the src struct into the dst struct.
+func (c *converter) fields(path string, sel []string, dstAll, srcAll map[string]transpiler.Struct, dst, src transpiler.Struct, depth int) []jen.Code {
+	res := []jen.Code{}
+
+	if depth > maxConversionDepth {
+		return append(res, jen.Comment(fmt.Sprintf("TODO: convert %s", path)))
+	}
+
+	srcFields := make(map[string]transpiler.Field, len(src.Fields))
+	for _, el := range src.Fields {
+		srcFields[conversionKey(el)] = el
+	}
+
+	keys := make([]string, 0, len(dst.Fields))
+	dstFields := make(map[string]transpiler.Field, len(dst.Fields))
+	for _, el := range dst.Fields {
+		keys = append(keys, conversionKey(el))
+		dstFields[conversionKey(el)] = el
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		df := dstFields[key]
+		fp := fmt.Sprintf("%s.%s", path, key)
+
+		sf, ok := srcFields[key]
+		if !ok {
+			res = append(res, jen.Comment(fmt.Sprintf("TODO: %s has no source field, fill it for %s", fp, c.dstVersion)))
+			continue
+		}
+
+		dt, st := conversionType(df), conversionType(sf)
+		dsel := append(append([]string{}, sel...), df.Name)
+		ssel := append(append([]string{}, sel...), sf.Name)
+
+		dstRef, srcRef := refersStruct(dt, dstAll), refersStruct(st, srcAll)
+		switch {
+		case !dstRef && !srcRef && dt == st:
+			res = append(res, assign(dsel, ssel))
+
+		case !dstRef && !srcRef && dt == "*"+st:
+			res = append(res, jen.Block(
+				jen.Id("val").Op(":=").Add(selector("src", ssel)),
+				selector("dst", dsel).Op("=").Op("&").Id("val"),
+			))
+
+		case !dstRef && !srcRef && st == "*"+dt:
+			res = append(res, jen.If(selector("src", ssel).Op("!=").Nil()).Block(
+				selector("dst", dsel).Op("=").Op("*").Add(selector("src", ssel)),
+			))
+
+		case dstRef && srcRef && isStructPointer(dt, dstAll) && isStructPointer(st, srcAll):
+			name := strings.TrimPrefix(dt, "*")
+			body := []jen.Code{
+				selector("dst", dsel).Op("=").Op("&").Add(c.typeName(name)).Values(),
+			}
+			body = append(body, c.fields(fp, dsel, dstAll, srcAll,
+				dstAll[name], srcAll[strings.TrimPrefix(st, "*")], depth+1)...)
+
+			res = append(res, jen.If(selector("src", ssel).Op("!=").Nil()).Block(body...))
+
+		default:
+			res = append(res, jen.Comment(fmt.Sprintf("TODO: convert %s (%s -> %s)", fp, st, dt)))
+		}
+	}
+
+	srcKeys := make([]string, 0, len(srcFields))
+	for key := range srcFields {
+		if _, ok := dstFields[key]; !ok {
+			srcKeys = append(srcKeys, key)
+		}
+	}
+	sort.Strings(srcKeys)
+
+	for _, key := range srcKeys {
+		res = append(res, jen.Comment(fmt.Sprintf("TODO: %s.%s is not available in %s", path, key, c.dstVersion)))
+	}
+
+	return res
+}
+
+func assign(dst, src []string) *jen.Statement {
+	return selector("dst", dst).Op("=").Add(selector("src", src))
+}
+
+func (c *converter) typeName(name string) *jen.Statement {
+	if c.toHub {
+		return jen.Qual(c.hubPkg, name)
+	}
+	return jen.Id(name)
+}
+
+func selector(root string, sel []string) *jen.Statement {
+	res := jen.Id(root)
+	for _, el := range sel {
+		res = res.Dot(el)
+	}
+	return res
+}
+
+// conversionKey matches the fields of two versions by JSON name.
+func conversionKey(el transpiler.Field) string {
+	if el.JSONName != "" {
+		return el.JSONName
+	}
+	return el.Name
+}
+
+// conversionType returns the Go type of the field as rendered in types.go.
+func conversionType(el transpiler.Field) string {
+	if !el.Required && !strings.HasPrefix(el.Type, "*") {
+		return "*" + el.Type
+	}
+	return el.Type
+}
+
+func refersStruct(typ string, all map[string]transpiler.Struct) bool {
+	for {
+		switch {
+		case strings.HasPrefix(typ, "*"):
+			typ = strings.TrimPrefix(typ, "*")
+		case strings.HasPrefix(typ, "[]"):
+			typ = strings.TrimPrefix(typ, "[]")
+		case strings.HasPrefix(typ, "map[string]"):
+			typ = strings.TrimPrefix(typ, "map[string]")
+		default:
+			_, ok := all[typ]
+			return ok
+		}
+	}
+}
+
+func isStructPointer(typ string, all map[string]transpiler.Struct) bool {
+	name, ok := strings.CutPrefix(typ, "*")
+	if !ok {
+		return false
+	}
+	_, ok = all[name]
+	return ok
+}
@@ -4,15 +4,34 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/dave/jennifer/jen"
 )
 
 const (
-	pkgControllerGen = "sigs.k8s.io/controller-tools/cmd/controller-gen"
+	// PkgControllerGen is the controller-gen command.
+	PkgControllerGen = "sigs.k8s.io/controller-tools/cmd/controller-gen"
 )
 
-func Generate(wri io.Writer) error {
+// ControllerGenArgs returns the arguments of controller-gen run in a
+// directory whose path to the workdir root is root, e.g. '.' or '..'.
+func ControllerGenArgs(cfg Options, root string) []string {
+	res := []string{}
+	if !cfg.SkipDeepCopy {
+		res = append(res, "object:headerFile="+root+"/hack/boilerplate.go.txt")
+	}
+
+	crd := append([]string{"crdVersions=v1"}, cfg.CRDOptions...)
+
+	return append(res,
+		"paths=./...",
+		"crd:"+strings.Join(crd, ","),
+		"output:artifacts:config="+root+"/crds",
+	)
+}
+
+func Generate(wri io.Writer, cfg Options) error {
 	g := jen.NewFile("apis")
 
 	//g.HeaderComment("go:build generate")
@@ -21,17 +40,21 @@ func Generate(wri io.Writer) error {
 	g.HeaderComment("Remove existing CRDs")
 	g.HeaderComment("go:generate rm -rf ../crds")
 	g.Line().Line()
-	g.HeaderComment("Generate deepcopy methodsets and CRD manifests")
-	g.HeaderComment("go:generate go run -tags generate sigs.k8s.io/controller-tools/cmd/controller-gen object:headerFile=../hack/boilerplate.go.txt paths=./... crd:crdVersions=v1 output:artifacts:config=../crds")
+	if cfg.SkipDeepCopy {
+		g.HeaderComment("Generate CRD manifests")
+	} else {
+		g.HeaderComment("Generate deepcopy methodsets and CRD manifests")
+	}
+	g.HeaderComment("go:generate go run -tags generate " + PkgControllerGen + " " + strings.Join(ControllerGenArgs(cfg, ".."), " "))
 	g.Line()
 
-	g.Anon(pkgControllerGen)
+	g.Anon(PkgControllerGen)
 
 	return g.Render(wri)
 }
 
-func CreateGenerateDotGo(workdir string) error {
-	path, err := makeDirs(workdir, "apis")
+func CreateGenerateDotGo(cfg Options) error {
+	path, err := makeDirs(cfg.Workdir, "apis")
 	if err != nil {
 		return err
 	}
@@ -42,5 +65,5 @@ func CreateGenerateDotGo(workdir string) error {
 	}
 	defer src.Close()
 
-	return Generate(src)
+	return Generate(src, cfg)
 }
@@ -5,7 +5,6 @@ import (
 	"io"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/dave/jennifer/jen"
 	"github.com/krateoplatformops/crdgen/internal/strutil"
@@ -18,7 +17,11 @@ const (
 	pkgControllerRuntimeSchemeAlias = "scheme"
 )
 
-func GroupVersionInfo(res *Resource, wri io.Writer) error {
+// GroupVersionInfo renders the group version info of a package:
+// all the resources must share the same group and version.
+func GroupVersionInfo(all []*Resource, wri io.Writer) error {
+	res := all[0]
+
 	g := jen.NewFile(normalizeVersion(res.Version))
 	g.PackageComment("+kubebuilder:object:generate=true")
 	g.PackageComment(fmt.Sprintf("+groupName=%s", res.Group))
@@ -30,17 +33,28 @@ func GroupVersionInfo(res *Resource, wri io.Writer) error {
 	g.Add(generateConsts(res))
 	g.Add(jen.Line())
 
-	g.Add(generateVars(res))
+	g.Add(generateVars(all))
 	g.Add(jen.Line())
 
-	g.Add(generateInitFunc(res))
+	g.Add(generateInitFunc(all))
 	g.Add(jen.Line())
 
 	return g.Render(wri)
 }
 
-func CreateGroupVersionInfoDotGo(workdir string, res *Resource) error {
-	path, err := makeDirs(workdir, "apis", strings.ToLower(res.Kind), normalizeVersion(res.Version))
+func CreateGroupVersionInfoDotGo(workdir string, all []*Resource) error {
+	if len(all) == 0 {
+		return nil
+	}
+
+	for _, el := range all[1:] {
+		if el.Group != all[0].Group || el.Version != all[0].Version {
+			return fmt.Errorf("cannot register %s/%s into the package of %s/%s",
+				el.Group, el.Version, all[0].Group, all[0].Version)
+		}
+	}
+
+	path, err := makeDirs(workdir, all[0].packageDir())
 	if err != nil {
 		return err
 	}
@@ -51,7 +65,7 @@ func CreateGroupVersionInfoDotGo(workdir string, res *Resource) error {
 	}
 	defer src.Close()
 
-	return GroupVersionInfo(res, src)
+	return GroupVersionInfo(all, src)
 }
 
 func generateConsts(res *Resource) jen.Code {
@@ -61,9 +75,7 @@ func generateConsts(res *Resource) jen.Code {
 	)
 }
 
-func generateVars(res *Resource) jen.Code {
-	kind := strutil.ToGolangName(res.Kind)
-
+func generateVars(all []*Resource) jen.Code {
 	code := jen.Var().Defs(
 		jen.Id("SchemeGroupVersion").Op("=").Qual(pkgRuntimeSchema, "GroupVersion").Values(
 			jen.Dict{
@@ -79,9 +91,18 @@ func generateVars(res *Resource) jen.Code {
 		),
 	)
 
-	code.Line().Line()
+	for _, res := range all {
+		code.Line().Line()
+		code.Add(generateKindVars(res))
+	}
+
+	return code
+}
 
-	code.Var().Defs(
+func generateKindVars(res *Resource) jen.Code {
+	kind := strutil.ToGolangName(res.Kind)
+
+	return jen.Var().Defs(
 		jen.Id(fmt.Sprintf("%sKind", kind)).
 			Op("=").
 			Qual("reflect", "TypeOf").
@@ -108,17 +129,18 @@ func generateVars(res *Resource) jen.Code {
 			Id("SchemeGroupVersion").Dot("WithKind").
 			Call(jen.Id(fmt.Sprintf("%sKind", kind))),
 	)
-
-	return code
 }
 
-func generateInitFunc(res *Resource) jen.Code {
-	kind := strutil.ToGolangName(res.Kind)
+func generateInitFunc(all []*Resource) jen.Code {
+	types := make([]jen.Code, 0, 2*len(all))
+	for _, res := range all {
+		types = append(types,
+			jen.Op("&").Id(strutil.ToGolangName(res.Kind)).Values(jen.Dict{}),
+			jen.Op("&").Id(res.ListKindName()).Values(jen.Dict{}),
+		)
+	}
 
 	return jen.Func().Id("init").Params().Block(
-		jen.Id("SchemeBuilder").Dot("Register").Call(
-			jen.Op("&").Id(kind).Values(jen.Dict{}),
-			jen.Op("&").Id(fmt.Sprintf("%sList", kind)).Values(jen.Dict{}),
-		),
+		jen.Id("SchemeBuilder").Dot("Register").Call(types...),
 	)
 }
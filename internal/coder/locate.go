@@ -0,0 +1,169 @@
+package coder
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/krateoplatformops/crdgen/internal/strutil"
+	"github.com/krateoplatformops/crdgen/internal/transpiler"
+)
+
+// Location is the schema property a generated Go declaration comes from.
+type Location struct {
+	Resource *Resource
+	// Property is the JSON path of the property, e.g. 'spec.address.zip';
+	// array items are marked by '[*]' and map values by '.*'.
+	Property string
+}
+
+// Locate maps a position of a file generated in workdir back to the
+// schema property of the field (or of the struct) declared there.
+func Locate(all []*Resource, workdir, file string, line int) (Location, bool) {
+	rel := file
+	if filepath.IsAbs(file) {
+		var err error
+		rel, err = filepath.Rel(workdir, file)
+		if err != nil {
+			return Location{}, false
+		}
+	}
+	rel = filepath.ToSlash(rel)
+
+	candidates := []*Resource{}
+	for _, el := range all {
+		if path.Dir(rel) == el.packageDir() {
+			candidates = append(candidates, el)
+		}
+	}
+	if len(candidates) == 0 {
+		return Location{}, false
+	}
+
+	typ, field, ok := declarationAt(filepath.Join(workdir, filepath.FromSlash(rel)), line)
+	if !ok {
+		return Location{}, false
+	}
+
+	key := typ
+	if len(field) > 0 {
+		key = typ + "." + field
+	}
+
+	for _, el := range candidates {
+		props, err := el.properties()
+		if err != nil {
+			continue
+		}
+
+		if prop, ok := props[key]; ok {
+			return Location{Resource: el, Property: prop}, true
+		}
+	}
+
+	return Location{}, false
+}
+
+// declarationAt returns the names of the struct type and of
+// its field (if any) declared at the line of the Go file.
+func declarationAt(filename string, line int) (typ, field string, ok bool) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, filename, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return "", "", false
+	}
+
+	within := func(n ast.Node) bool {
+		return fset.Position(n.Pos()).Line <= line && line <= fset.Position(n.End()).Line
+	}
+
+	for _, decl := range f.Decls {
+		gen, isGen := decl.(*ast.GenDecl)
+		if !isGen || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			st, isStruct := ts.Type.(*ast.StructType)
+			if !isStruct || !within(ts) {
+				continue
+			}
+
+			for _, fld := range st.Fields.List {
+				if within(fld) && len(fld.Names) > 0 {
+					return ts.Name.Name, fld.Names[0].Name, true
+				}
+			}
+			return ts.Name.Name, "", true
+		}
+	}
+
+	return "", "", false
+}
+
+// properties returns the JSON paths of the schema properties keyed by
+// generated struct name (e.g. 'XappSpec') and by struct and field name
+// (e.g. 'XappSpec.Address').
+func (r *Resource) properties() (map[string]string, error) {
+	kind := strutil.ToGolangName(r.Kind)
+	res := map[string]string{}
+
+	spec, err := r.specStructs()
+	if err != nil {
+		return nil, err
+	}
+	walkProperties(spec, "Root", strutil.ToGolangName(fmt.Sprintf("%sSpec", kind)), "spec", res)
+
+	if len(r.StatusSchema) > 0 {
+		status, err := r.statusStructs()
+		if err != nil {
+			return nil, err
+		}
+		walkProperties(status, "Root", strutil.ToGolangName(fmt.Sprintf("%sStatus", kind)), "status", res)
+	}
+
+	return res, nil
+}
+
+// walkProperties records the path of the struct and of its fields,
+// descending into the nested structs not yet visited.
+func walkProperties(all map[string]transpiler.Struct, key, name, prefix string, res map[string]string) {
+	if _, ok := res[name]; ok {
+		return
+	}
+	res[name] = prefix
+
+	for _, f := range all[key].Fields {
+		prop := prefix
+		if len(f.JSONName) > 0 {
+			prop = prefix + "." + f.JSONName
+		}
+
+		res[name+"."+f.Name] = prop
+
+		nested := ""
+		typ := renameType(f.Type, func(base string) string {
+			nested = base
+			return base
+		})
+		for mods := strings.TrimSuffix(typ, nested); len(mods) > 0; {
+			switch {
+			case strings.HasPrefix(mods, "*"):
+				mods = strings.TrimPrefix(mods, "*")
+			case strings.HasPrefix(mods, "[]"):
+				prop, mods = prop+"[*]", strings.TrimPrefix(mods, "[]")
+			case strings.HasPrefix(mods, "map[string]"):
+				prop, mods = prop+".*", strings.TrimPrefix(mods, "map[string]")
+			}
+		}
+
+		if _, ok := all[nested]; ok && nested != "Root" {
+			walkProperties(all, nested, nested, prop, res)
+		}
+	}
+}
@@ -3,14 +3,13 @@ package coder
 import (
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/dave/jennifer/jen"
 	"github.com/krateoplatformops/crdgen/internal/strutil"
 )
 
 func GenerateManaged(workdir string, res *Resource) error {
-	path, err := makeDirs(workdir, "apis", strings.ToLower(res.Kind), normalizeVersion(res.Version))
+	path, err := makeDirs(workdir, res.packageDir())
 	if err != nil {
 		return err
 	}
@@ -21,7 +20,7 @@ func GenerateManaged(workdir string, res *Resource) error {
 	g.Add(generateConditionFuncs(res))
 	g.Line()
 
-	src, err := os.Create(filepath.Join(path, "managed.go"))
+	src, err := os.Create(filepath.Join(path, fileName(res, "managed.go")))
 	if err != nil {
 		return err
 	}
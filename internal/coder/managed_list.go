@@ -1,13 +1,10 @@
 package coder
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/dave/jennifer/jen"
-	"github.com/krateoplatformops/crdgen/internal/strutil"
 )
 
 const (
@@ -16,18 +13,16 @@ const (
 )
 
 func GenerateManagedList(workdir string, res *Resource) error {
-	path, err := makeDirs(workdir, "apis", strings.ToLower(res.Kind), normalizeVersion(res.Version))
+	path, err := makeDirs(workdir, res.packageDir())
 	if err != nil {
 		return err
 	}
 
-	kind := strutil.ToGolangName(res.Kind)
-
 	g := jen.NewFile(normalizeVersion(res.Version))
 	g.ImportAlias(pkgResource, pkgResourceAlias)
 
 	g.Add(
-		jen.Func().Params(jen.Id("ml").Op("*").Id(fmt.Sprintf("%sList", kind))).
+		jen.Func().Params(jen.Id("ml").Op("*").Id(res.ListKindName())).
 			Id("GetItems").Params().Index().Qual(pkgResource, "Managed").
 			Block(
 				jen.Id("items").Op(":=").Make(
@@ -46,7 +41,7 @@ func GenerateManagedList(workdir string, res *Resource) error {
 	)
 	g.Line()
 
-	src, err := os.Create(filepath.Join(path, "managed_list.go"))
+	src, err := os.Create(filepath.Join(path, fileName(res, "managed_list.go")))
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,242 @@
+package coder
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/krateoplatformops/crdgen/internal/strutil"
+	"github.com/krateoplatformops/crdgen/internal/transpiler"
+)
+
+// DefaultLayout is the directory of the API packages relative to the module root.
+const DefaultLayout = "apis/{group}/{version}"
+
+// apiPackage holds the resources generated in the same Go package:
+// all the kinds sharing group and version.
+type apiPackage struct {
+	group     string
+	version   string
+	resources []*Resource
+}
+
+// dir returns the package directory relative to the module root,
+// e.g. 'apis/example.org/v1alpha1'.
+func (p *apiPackage) dir() string {
+	return p.resources[0].packageDir()
+}
+
+// alias returns the import alias of the package, e.g. 'exampleorgv1alpha1'.
+func (p *apiPackage) alias() string {
+	return packageAlias(p.group, p.version)
+}
+
+// packageDir returns the package directory of the resource.
+func (r *Resource) packageDir() string {
+	if len(r.dir) > 0 {
+		return r.dir
+	}
+	return packageDir(DefaultLayout, r.Group, r.Version)
+}
+
+// packageDir expands the layout placeholders: '{group}' (lowercase),
+// '{shortGroup}' (the first label of the group) and '{version}'.
+func packageDir(layout, group, version string) string {
+	group = strings.ToLower(group)
+	short, _, _ := strings.Cut(group, ".")
+
+	return path.Clean(strings.NewReplacer(
+		"{group}", group,
+		"{shortGroup}", short,
+		"{version}", normalizeVersion(version),
+	).Replace(layout))
+}
+
+// ValidateLayout checks that the layout is a relative, slash separated
+// path naming a directory per version, e.g. 'api/{shortGroup}/{version}'.
+func ValidateLayout(layout string) error {
+	if !strings.Contains(layout, "{version}") {
+		return fmt.Errorf("invalid layout '%s': missing {version}", layout)
+	}
+
+	if !fs.ValidPath(layout) || strings.Contains(layout, `\`) {
+		return fmt.Errorf("invalid layout '%s': must be a relative, slash separated path", layout)
+	}
+
+	for _, el := range strings.Split(layout, "/") {
+		if el == "vendor" || el == "testdata" || strings.HasPrefix(el, "_") || strings.HasPrefix(el, ".") {
+			return fmt.Errorf("invalid layout '%s': the go command ignores '%s'", layout, el)
+		}
+	}
+
+	return nil
+}
+
+func packageAlias(group, version string) string {
+	alias := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return -1
+	}, strings.ToLower(group+version))
+
+	if len(alias) == 0 || (alias[0] >= '0' && alias[0] <= '9') {
+		alias = "api" + alias
+	}
+	return alias
+}
+
+// packagePath returns the import path of the package of the resource.
+func packagePath(module string, res *Resource) string {
+	return path.Join(module, res.packageDir())
+}
+
+// groupPackages groups the resources by package, in order of appearance,
+// placing every package according to the layout; when several kinds
+// share a package their nested structs are prefixed with the kind name
+// to keep the type names unique.
+func groupPackages(all []*Resource, layout string) ([]*apiPackage, error) {
+	if len(layout) == 0 {
+		layout = DefaultLayout
+	}
+	if err := ValidateLayout(layout); err != nil {
+		return nil, err
+	}
+
+	res := []*apiPackage{}
+	idx := map[string]*apiPackage{}
+	dirs := map[string]*apiPackage{}
+
+	for _, el := range all {
+		el.dir = packageDir(layout, el.Group, el.Version)
+
+		key := strings.ToLower(el.Group) + "/" + el.Version
+		pkg, ok := idx[key]
+		if !ok {
+			if other, ok := dirs[el.dir]; ok {
+				return nil, fmt.Errorf("packages of %s/%s and %s/%s share the directory '%s'",
+					other.group, other.version, el.Group, el.Version, el.dir)
+			}
+
+			pkg = &apiPackage{group: el.Group, version: el.Version}
+			idx[key] = pkg
+			dirs[el.dir] = pkg
+			res = append(res, pkg)
+		}
+		pkg.resources = append(pkg.resources, el)
+	}
+
+	for _, pkg := range res {
+		names := map[string]string{}
+		for _, el := range pkg.resources {
+			kind := strutil.ToGolangName(el.Kind)
+			for _, id := range []string{kind, el.ListKindName()} {
+				if other, ok := names[id]; ok {
+					return nil, fmt.Errorf("type '%s' of kind '%s' clashes with kind '%s' in %s/%s",
+						id, el.Kind, other, pkg.group, pkg.version)
+				}
+				names[id] = el.Kind
+			}
+
+			if len(pkg.resources) > 1 {
+				el.typePrefix = kind
+			}
+		}
+	}
+
+	aliases := map[string]string{}
+	for _, pkg := range res {
+		if other, ok := aliases[pkg.alias()]; ok {
+			return nil, fmt.Errorf("packages '%s' and '%s' share the same import alias", other, pkg.dir())
+		}
+		aliases[pkg.alias()] = pkg.dir()
+	}
+
+	return res, nil
+}
+
+// groupKinds groups the versions of every kind, in order of appearance.
+func groupKinds(all []*Resource) [][]*Resource {
+	res := [][]*Resource{}
+	idx := map[string]int{}
+
+	for _, el := range all {
+		key := el.Group + "/" + el.Kind
+		i, ok := idx[key]
+		if !ok {
+			i = len(res)
+			idx[key] = i
+			res = append(res, nil)
+		}
+		res[i] = append(res[i], el)
+	}
+
+	return res
+}
+
+// prefixStructs renames the nested structs of the model (all but
+// 'Root') adding the prefix, updating the field types referring them.
+func prefixStructs(all map[string]transpiler.Struct, prefix string) map[string]transpiler.Struct {
+	if len(prefix) == 0 {
+		return all
+	}
+
+	rename := func(name string) string {
+		if name == "Root" {
+			return name
+		}
+		return prefix + name
+	}
+
+	res := make(map[string]transpiler.Struct, len(all))
+	for key, el := range all {
+		fields := make(map[string]transpiler.Field, len(el.Fields))
+		for k, f := range el.Fields {
+			f.Type = renameType(f.Type, func(name string) string {
+				if _, ok := all[name]; ok {
+					return rename(name)
+				}
+				return name
+			})
+			fields[k] = f
+		}
+
+		el.Fields = fields
+		if el.Name != "" {
+			el.Name = rename(el.Name)
+		}
+		res[rename(key)] = el
+	}
+
+	return res
+}
+
+// renameType applies fn to the base type name of a
+// composite type, e.g. '[]*Foo' => '[]*' + fn("Foo").
+func renameType(typ string, fn func(string) string) string {
+	mods := ""
+	for {
+		switch {
+		case strings.HasPrefix(typ, "*"):
+			mods, typ = mods+"*", strings.TrimPrefix(typ, "*")
+		case strings.HasPrefix(typ, "[]"):
+			mods, typ = mods+"[]", strings.TrimPrefix(typ, "[]")
+		case strings.HasPrefix(typ, "map[string]"):
+			mods, typ = mods+"map[string]", strings.TrimPrefix(typ, "map[string]")
+		default:
+			return mods + fn(typ)
+		}
+	}
+}
+
+// sortedKeys returns the keys of the model in lexical order.
+func sortedKeys(all map[string]transpiler.Struct) []string {
+	keys := make([]string, 0, len(all))
+	for k := range all {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
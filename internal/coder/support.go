@@ -1,12 +1,14 @@
 package coder
 
 import (
+	"bytes"
 	"errors"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/krateoplatformops/crdgen/internal/strutil"
 	"github.com/krateoplatformops/crdgen/internal/transpiler"
 	"github.com/krateoplatformops/crdgen/internal/transpiler/jsonschema"
 )
@@ -38,3 +40,43 @@ func jsonschemaToStruct(r io.Reader) (map[string]transpiler.Struct, error) {
 
 	return transpiler.Transpile(schema)
 }
+
+// fileName returns the name of a per kind file, e.g. 'xapp_types.go'.
+func fileName(res *Resource, suffix string) string {
+	return strings.ToLower(strutil.ToGolangName(res.Kind)) + "_" + suffix
+}
+
+// specStructs transpiles the spec JSON schema of the resource.
+func (r *Resource) specStructs() (map[string]transpiler.Struct, error) {
+	all, err := jsonschemaToStruct(bytes.NewReader(r.SpecSchema))
+	if err != nil {
+		return nil, err
+	}
+
+	return prefixStructs(all, r.typePrefix), nil
+}
+
+// statusStructs transpiles the status JSON schema of the resource,
+// returning an empty root when the status schema is not set.
+func (r *Resource) statusStructs() (map[string]transpiler.Struct, error) {
+	if len(r.StatusSchema) == 0 {
+		return map[string]transpiler.Struct{
+			"Root": {
+				Name:   "Root",
+				Fields: make(map[string]transpiler.Field),
+			},
+		}, nil
+	}
+
+	all, err := jsonschemaToStruct(bytes.NewReader(r.StatusSchema))
+	if err != nil {
+		return nil, err
+	}
+
+	return prefixStructs(all, r.typePrefix), nil
+}
+
+// hasStatus reports whether the resource has the status subresource.
+func (r *Resource) hasStatus() bool {
+	return len(r.StatusSchema) > 0 || r.Managed
+}
@@ -1,11 +1,11 @@
 package coder
 
 import (
-	"bytes"
 	"fmt"
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/dave/jennifer/jen"
@@ -23,16 +23,15 @@ const (
 	pkgMetaAlias   = "metav1"
 )
 
-func CreateTypesDotGo(workdir string, res *Resource) error {
-	path, err := makeDirs(workdir, "apis",
-		strings.ToLower(res.Kind), normalizeVersion(res.Version))
+func CreateTypesDotGo(workdir string, res *Resource, logger *log.Logger) error {
+	path, err := makeDirs(workdir, res.packageDir())
 	if err != nil {
 		return err
 	}
 
 	kind := strutil.ToGolangName(res.Kind)
 
-	spec, err := jsonschemaToStruct(bytes.NewReader(res.SpecSchema))
+	spec, err := res.specStructs()
 	if err != nil {
 		return err
 	}
@@ -41,51 +40,62 @@ func CreateTypesDotGo(workdir string, res *Resource) error {
 	g.ImportAlias(pkgCommon, pkgCommonAlias)
 	g.ImportAlias(pkgMeta, pkgMetaAlias)
 
-	log.Printf("[DBG] Generating code: %s/types.go\n", path)
+	logger.Printf("[DBG] Generating code: %s/%s\n", path, fileName(res, "types.go"))
 
-	for k, v := range spec {
-		log.Printf("[DBG] Creating struct for '%s': %s\n", k, spew.Sdump(v))
+	for _, k := range sortedKeys(spec) {
+		v := spec[k]
+		logger.Printf("[DBG] Creating struct for '%s': %s\n", k, spew.Sdump(v))
 		g.Add(renderSpec(kind, k, v))
 	}
 
 	g.Add(jen.Line())
 
-	hasStatus := len(res.StatusSchema) > 0 || res.Managed
+	hasStatus := res.hasStatus()
 	if hasStatus {
-		status := map[string]transpiler.Struct{
-			"Root": {
-				Name:   "Root",
-				Fields: make(map[string]transpiler.Field),
-			},
+		status, err := res.statusStructs()
+		if err != nil {
+			return err
 		}
-		if len(res.StatusSchema) > 0 {
-			status, err = jsonschemaToStruct(bytes.NewReader(res.StatusSchema))
-			if err != nil {
-				return err
-			}
-		}
-
-		g.Add(createFailedObjectRef())
-		g.Add(jen.Line())
 
-		for k, v := range status {
-			g.Add(renderStatus(kind, k, v, res.Managed))
+		for _, k := range sortedKeys(status) {
+			g.Add(renderStatus(kind, k, status[k], res.Managed))
 		}
 	}
 
+	scope := res.Scope
+	if len(scope) == 0 {
+		scope = ScopeNamespaced
+	}
+
 	g.Add(jen.Comment("+kubebuilder:object:root=true"))
 
 	if hasStatus {
 		g.Add(jen.Comment("+kubebuilder:subresource:status"))
 	}
 
+	if res.Storage {
+		g.Add(jen.Comment("+kubebuilder:storageversion"))
+	}
+
+	if !res.Served {
+		g.Add(jen.Comment("+kubebuilder:unservedversion"))
+	}
+
+	if len(res.DeprecationWarning) > 0 {
+		g.Add(jen.Comment(fmt.Sprintf("+kubebuilder:deprecatedversion:warning=%q", res.DeprecationWarning)))
+	} else if res.Deprecated {
+		g.Add(jen.Comment("+kubebuilder:deprecatedversion"))
+	}
+
+	marker := fmt.Sprintf("+kubebuilder:resource:path=%s,singular=%s,scope=%s",
+		res.PluralName(), res.SingularName(), scope)
+	if len(res.ShortNames) > 0 {
+		marker = fmt.Sprintf("%s,shortName={%s}", marker, strings.Join(res.ShortNames, ","))
+	}
 	if len(res.Categories) > 0 {
-		g.Add(jen.Comment(
-			fmt.Sprintf("+kubebuilder:resource:scope=Namespaced,categories={%s}",
-				strings.Join(res.Categories, ","))))
-	} else {
-		g.Add(jen.Comment("+kubebuilder:resource:scope=Namespaced"))
+		marker = fmt.Sprintf("%s,categories={%s}", marker, strings.Join(res.Categories, ","))
 	}
+	g.Add(jen.Comment(marker))
 
 	if hasStatus {
 		g.Add(jen.Comment(`+kubebuilder:printcolumn:name="AGE",type="date",JSONPath=".metadata.creationTimestamp"`))
@@ -116,14 +126,14 @@ func CreateTypesDotGo(workdir string, res *Resource) error {
 	g.Add(jen.Comment("+kubebuilder:object:root=true"))
 	g.Add(jen.Line())
 
-	g.Add(jen.Type().Id(fmt.Sprintf("%sList", kind)).Struct(
+	g.Add(jen.Type().Id(res.ListKindName()).Struct(
 		jen.Qual(pkgMeta, "TypeMeta").Tag(map[string]string{"json": ",inline"}),
 		jen.Qual(pkgMeta, "ListMeta").Tag(map[string]string{"json": "metadata,omitempty"}),
 		jen.Line(),
 		jen.Id("Items").Id(fmt.Sprintf("[]%s", kind)).Tag(map[string]string{"json": "items"}),
 	).Line())
 
-	src, err := os.Create(filepath.Join(path, "types.go"))
+	src, err := os.Create(filepath.Join(path, fileName(res, "types.go")))
 	if err != nil {
 		return err
 	}
@@ -189,19 +199,19 @@ func renderField(el transpiler.Field) jen.Code {
 
 	if el.Minimum != nil {
 		val := ptr.Deref(el.Minimum, 0)
-		cmt := fmt.Sprintf("+kubebuilder:validation:Minimum:=%d", int(val))
+		cmt := fmt.Sprintf("+kubebuilder:validation:Minimum:=%s", strconv.FormatFloat(val, 'f', -1, 64))
 		res.Add(jen.Comment(cmt).Line())
 	}
 
 	if el.Maximum != nil {
 		val := ptr.Deref(el.Maximum, 0)
-		cmt := fmt.Sprintf("+kubebuilder:validation:Maximum:=%d", int(val))
+		cmt := fmt.Sprintf("+kubebuilder:validation:Maximum:=%s", strconv.FormatFloat(val, 'f', -1, 64))
 		res.Add(jen.Comment(cmt).Line())
 	}
 
 	if el.MultipleOf != nil {
 		val := ptr.Deref(el.MultipleOf, 0)
-		cmt := fmt.Sprintf("+kubebuilder:validation:MultipleOf:=%d", int(val))
+		cmt := fmt.Sprintf("+kubebuilder:validation:MultipleOf:=%s", strconv.FormatFloat(val, 'f', -1, 64))
 		res.Add(jen.Comment(cmt).Line())
 	}
 
@@ -269,8 +279,47 @@ func renderStatus(kind, key string, el transpiler.Struct, managed bool) jen.Code
 	return res
 }
 
+// CreateFailedObjectRefDotGo generates the FailedObjectRef type
+// shared by all the kinds of the package of the resource.
+func CreateFailedObjectRefDotGo(workdir string, res *Resource) error {
+	path, err := makeDirs(workdir, res.packageDir())
+	if err != nil {
+		return err
+	}
+
+	g := jen.NewFile(normalizeVersion(res.Version))
+	g.Add(createFailedObjectRef())
+
+	src, err := os.Create(filepath.Join(path, "failedobjectref.go"))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	return g.Render(src)
+}
+
+// hasFailedObjectRef reports whether any of the resources needs FailedObjectRef.
+func hasFailedObjectRef(all []*Resource) bool {
+	for _, el := range all {
+		if el.hasStatus() {
+			return true
+		}
+	}
+	return false
+}
+
 func createFailedObjectRef() jen.Code {
-	meta := []transpiler.Field{
+	fields := []jen.Code{}
+	for _, el := range failedObjectRefFields() {
+		fields = append(fields, renderField(el))
+	}
+
+	return jen.Type().Id("FailedObjectRef").Struct(fields...).Line()
+}
+
+func failedObjectRefFields() []transpiler.Field {
+	return []transpiler.Field{
 		{
 			Name:        "APIVersion",
 			JSONName:    "apiVersion",
@@ -296,11 +345,4 @@ func createFailedObjectRef() jen.Code {
 			Type:        "string",
 		},
 	}
-
-	fields := []jen.Code{}
-	for _, el := range meta {
-		fields = append(fields, renderField(el))
-	}
-
-	return jen.Type().Id("FailedObjectRef").Struct(fields...).Line()
 }
@@ -0,0 +1,202 @@
+package crd
+
+import (
+	"fmt"
+
+	"github.com/krateoplatformops/crdgen/internal/coder"
+	"github.com/krateoplatformops/crdgen/internal/ptr"
+	"github.com/krateoplatformops/crdgen/internal/transpiler"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Build creates the CustomResourceDefinition of the specified resource
+// versions straight from the transpiled JSON schemas, without generating
+// any Go code. All the versions must belong to the same group and kind.
+func Build(all ...*coder.Resource) (*apiextensionsv1.CustomResourceDefinition, error) {
+	if len(all) == 0 {
+		return nil, fmt.Errorf("no resource versions to build")
+	}
+
+	res := all[0]
+	if res.Group == "" || res.Kind == "" {
+		return nil, fmt.Errorf("incomplete group or kind: %s, %s", res.Group, res.Kind)
+	}
+
+	scope := apiextensionsv1.ResourceScope(res.Scope)
+	if len(scope) == 0 {
+		scope = apiextensionsv1.NamespaceScoped
+	}
+
+	plural := res.PluralName()
+
+	obj := &apiextensionsv1.CustomResourceDefinition{
+		TypeMeta: metav1.TypeMeta{
+			APIVersion: apiextensionsv1.SchemeGroupVersion.String(),
+			Kind:       "CustomResourceDefinition",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: fmt.Sprintf("%s.%s", plural, res.Group),
+		},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: res.Group,
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind:       res.Kind,
+				ListKind:   res.ListKindName(),
+				Plural:     plural,
+				Singular:   res.SingularName(),
+				ShortNames: res.ShortNames,
+				Categories: res.Categories,
+			},
+			Scope:    scope,
+			Versions: make([]apiextensionsv1.CustomResourceDefinitionVersion, 0, len(all)),
+		},
+	}
+
+	storage := 0
+	for _, el := range all {
+		if el.Group != res.Group || el.Kind != res.Kind {
+			return nil, fmt.Errorf("version '%s' belongs to %s, %s (expected: %s, %s)",
+				el.Version, el.Group, el.Kind, res.Group, res.Kind)
+		}
+
+		if el.Scope != res.Scope {
+			return nil, fmt.Errorf("version '%s' has scope '%s' (expected: %s)",
+				el.Version, el.Scope, res.Scope)
+		}
+
+		if el.Version == "" {
+			return nil, fmt.Errorf("missing version for %s, %s", el.Group, el.Kind)
+		}
+
+		if el.Storage {
+			storage++
+		}
+
+		ver, err := buildVersion(el)
+		if err != nil {
+			return nil, fmt.Errorf("version '%s': %w", el.Version, err)
+		}
+		obj.Spec.Versions = append(obj.Spec.Versions, ver)
+	}
+
+	if storage != 1 {
+		return nil, fmt.Errorf("exactly one version must be marked as storage (found: %d)", storage)
+	}
+
+	return obj, nil
+}
+
+// SchemaError reports a JSON schema of a resource version that cannot
+// be turned in an OpenAPI v3 schema, e.g. with an unknown or recursive type.
+type SchemaError struct {
+	Version string
+	// Schema is either "spec" or "status".
+	Schema string
+	Err    error
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s schema: %v", e.Schema, e.Err)
+}
+
+func (e *SchemaError) Unwrap() error { return e.Err }
+
+// Schema returns the OpenAPI v3 schema Build emits for the
+// spec of a resource with the JSON schema.
+func Schema(data []byte) (*apiextensionsv1.JSONSchemaProps, error) {
+	all, err := transpile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := newSchemaBuilder(all).object(all["Root"])
+	if err != nil {
+		return nil, err
+	}
+	return &res, nil
+}
+
+func buildVersion(res *coder.Resource) (ver apiextensionsv1.CustomResourceDefinitionVersion, err error) {
+	spec, err := transpile(res.SpecSchema)
+	if err != nil {
+		return ver, &SchemaError{Version: res.Version, Schema: "spec", Err: err}
+	}
+
+	root := apiextensionsv1.JSONSchemaProps{
+		Description: spec["Root"].Description,
+		Type:        "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"apiVersion": {Description: apiVersionDescription, Type: "string"},
+			"kind":       {Description: kindDescription, Type: "string"},
+			"metadata":   {Type: "object"},
+		},
+	}
+
+	root.Properties["spec"], err = newSchemaBuilder(spec).object(spec["Root"])
+	if err != nil {
+		return ver, &SchemaError{Version: res.Version, Schema: "spec", Err: err}
+	}
+
+	ver = apiextensionsv1.CustomResourceDefinitionVersion{
+		Name:       res.Version,
+		Served:     res.Served,
+		Storage:    res.Storage,
+		Deprecated: res.Deprecated || len(res.DeprecationWarning) > 0,
+		Schema: &apiextensionsv1.CustomResourceValidation{
+			OpenAPIV3Schema: &root,
+		},
+	}
+
+	if len(res.DeprecationWarning) > 0 {
+		ver.DeprecationWarning = ptr.To(res.DeprecationWarning)
+	}
+
+	hasStatus := len(res.StatusSchema) > 0 || res.Managed
+	if !hasStatus {
+		return ver, nil
+	}
+
+	status := map[string]transpiler.Struct{
+		"Root": {
+			Name:   "Root",
+			Fields: make(map[string]transpiler.Field),
+		},
+	}
+	if len(res.StatusSchema) > 0 {
+		status, err = transpile(res.StatusSchema)
+		if err != nil {
+			return ver, &SchemaError{Version: res.Version, Schema: "status", Err: err}
+		}
+	}
+
+	el, err := newSchemaBuilder(status).object(status["Root"])
+	if err != nil {
+		return ver, &SchemaError{Version: res.Version, Schema: "status", Err: err}
+	}
+
+	if res.Managed {
+		if el.Properties == nil {
+			el.Properties = make(map[string]apiextensionsv1.JSONSchemaProps, 2)
+		}
+		el.Properties["conditions"] = conditionsSchema()
+		el.Properties["failedObjectRef"] = failedObjectRefSchema()
+	}
+	root.Properties["status"] = el
+
+	ver.Subresources = &apiextensionsv1.CustomResourceSubresources{
+		Status: &apiextensionsv1.CustomResourceSubresourceStatus{},
+	}
+
+	ver.AdditionalPrinterColumns = []apiextensionsv1.CustomResourceColumnDefinition{
+		{Name: "AGE", Type: "date", JSONPath: ".metadata.creationTimestamp"},
+	}
+	if res.Managed {
+		ver.AdditionalPrinterColumns = append(ver.AdditionalPrinterColumns,
+			apiextensionsv1.CustomResourceColumnDefinition{
+				Name: "READY", Type: "string", JSONPath: ".status.conditions[?(@.type=='Ready')].status",
+			})
+	}
+
+	return ver, nil
+}
@@ -0,0 +1,294 @@
+package crd
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen/internal/coder"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+func TestBuild(t *testing.T) {
+	spec, err := os.ReadFile("../../testdata/issue.43.hack.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err := Build(&coder.Resource{
+		Group:      "example.org",
+		Version:    "v1alpha1",
+		Kind:       "Xapp",
+		Categories: []string{"krateo"},
+		SpecSchema: spec,
+		Managed:    true,
+		Served:     true,
+		Storage:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := obj.Name, "xapps.example.org"; got != want {
+		t.Errorf("expected name %q, got %q", want, got)
+	}
+
+	if got := obj.Spec.Names.Categories; !slices.Equal(got, []string{"krateo"}) {
+		t.Errorf("unexpected categories: %v", got)
+	}
+
+	if len(obj.Spec.Versions) != 1 {
+		t.Fatalf("expected 1 version, got %d", len(obj.Spec.Versions))
+	}
+
+	ver := obj.Spec.Versions[0]
+	if !ver.Served || !ver.Storage {
+		t.Errorf("expected version to be served and storage")
+	}
+
+	if ver.Subresources == nil || ver.Subresources.Status == nil {
+		t.Errorf("expected status subresource")
+	}
+
+	if got := len(ver.AdditionalPrinterColumns); got != 2 {
+		t.Errorf("expected 2 printer columns, got %d", got)
+	}
+
+	root := ver.Schema.OpenAPIV3Schema
+	spc := root.Properties["spec"]
+	if !slices.Equal(spc.Required, []string{"app", "infra"}) {
+		t.Errorf("unexpected spec required: %v", spc.Required)
+	}
+
+	port := spc.Properties["app"].Properties["service"].Properties["port"]
+	if port.Type != "integer" {
+		t.Errorf("expected integer port, got %q", port.Type)
+	}
+	if port.Minimum == nil || *port.Minimum != 30000 {
+		t.Errorf("unexpected port minimum: %v", port.Minimum)
+	}
+	if port.Default == nil || string(port.Default.Raw) != "31180" {
+		t.Errorf("unexpected port default: %v", port.Default)
+	}
+
+	typ := spc.Properties["app"].Properties["service"].Properties["type"]
+	if len(typ.Enum) != 2 || string(typ.Enum[0].Raw) != `"NodePort"` {
+		t.Errorf("unexpected type enum: %v", typ.Enum)
+	}
+
+	status := root.Properties["status"]
+	if _, ok := status.Properties["conditions"]; !ok {
+		t.Errorf("expected conditions in managed status")
+	}
+}
+
+func TestBuildArrayEnums(t *testing.T) {
+	spec, err := os.ReadFile("../../testdata/array.enums.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj, err := Build(&coder.Resource{
+		Group:        "example.org",
+		Version:      "v1alpha1",
+		Kind:         "Xapp",
+		SpecSchema:   spec,
+		StatusSchema: []byte(`{"type": "object", "additionalProperties": true}`),
+		Served:       true,
+		Storage:      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := obj.Spec.Versions[0].Schema.OpenAPIV3Schema
+	fld := root.Properties["spec"].Properties["maybeAllowedResources"]
+	if fld.Type != "array" {
+		t.Fatalf("expected array, got %q", fld.Type)
+	}
+	if len(fld.Enum) != 0 {
+		t.Errorf("expected no enum on the array itself, got %v", fld.Enum)
+	}
+	if got := len(fld.Items.Schema.Enum); got != 4 {
+		t.Errorf("expected 4 enum values on items, got %d", got)
+	}
+	if string(fld.Default.Raw) != `["blue","green","red"]` {
+		t.Errorf("unexpected default: %s", fld.Default.Raw)
+	}
+
+	status := root.Properties["status"]
+	if status.XPreserveUnknownFields == nil || !*status.XPreserveUnknownFields {
+		t.Errorf("expected status to preserve unknown fields")
+	}
+
+	if len(obj.Spec.Versions[0].AdditionalPrinterColumns) != 1 {
+		t.Errorf("expected only the AGE printer column")
+	}
+}
+
+func TestBuildVersions(t *testing.T) {
+	spec := []byte(`{"type": "object", "properties": {"name": {"type": "string"}}}`)
+
+	obj, err := Build(
+		&coder.Resource{
+			Group: "example.org", Version: "v1alpha1", Kind: "Xapp",
+			SpecSchema: spec, Served: true,
+			DeprecationWarning: "example.org/v1alpha1 Xapp is deprecated",
+		},
+		&coder.Resource{
+			Group: "example.org", Version: "v1beta1", Kind: "Xapp",
+			SpecSchema: spec, Served: true, Storage: true,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(obj.Spec.Versions); got != 2 {
+		t.Fatalf("expected 2 versions, got %d", got)
+	}
+
+	old, cur := obj.Spec.Versions[0], obj.Spec.Versions[1]
+	if old.Storage || !cur.Storage {
+		t.Errorf("expected v1beta1 to be the storage version")
+	}
+	if !old.Deprecated || old.DeprecationWarning == nil {
+		t.Errorf("expected v1alpha1 to be deprecated with a warning")
+	}
+	if cur.Deprecated {
+		t.Errorf("expected v1beta1 not to be deprecated")
+	}
+}
+
+func TestBuildVersionsErrors(t *testing.T) {
+	spec := []byte(`{"type": "object"}`)
+
+	tests := []struct {
+		name string
+		all  []*coder.Resource
+	}{
+		{
+			name: "no storage",
+			all: []*coder.Resource{
+				{Group: "example.org", Version: "v1alpha1", Kind: "Xapp", SpecSchema: spec},
+			},
+		},
+		{
+			name: "two storages",
+			all: []*coder.Resource{
+				{Group: "example.org", Version: "v1alpha1", Kind: "Xapp", SpecSchema: spec, Storage: true},
+				{Group: "example.org", Version: "v1beta1", Kind: "Xapp", SpecSchema: spec, Storage: true},
+			},
+		},
+		{
+			name: "mixed kinds",
+			all: []*coder.Resource{
+				{Group: "example.org", Version: "v1alpha1", Kind: "Xapp", SpecSchema: spec, Storage: true},
+				{Group: "example.org", Version: "v1beta1", Kind: "Yapp", SpecSchema: spec},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := Build(tc.all...); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestBuildScope(t *testing.T) {
+	spec := []byte(`{"type": "object"}`)
+
+	obj, err := Build(&coder.Resource{
+		Group: "example.org", Version: "v1alpha1", Kind: "Tenant", Scope: coder.ScopeCluster,
+		SpecSchema: spec, Served: true, Storage: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := obj.Spec.Scope; got != apiextensionsv1.ClusterScoped {
+		t.Errorf("expected Cluster scope, got %q", got)
+	}
+
+	obj, err = Build(&coder.Resource{
+		Group: "example.org", Version: "v1alpha1", Kind: "Tenant",
+		SpecSchema: spec, Served: true, Storage: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := obj.Spec.Scope; got != apiextensionsv1.NamespaceScoped {
+		t.Errorf("expected Namespaced scope by default, got %q", got)
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	obj, err := Build(&coder.Resource{
+		Group:      "example.org",
+		Version:    "v1alpha1",
+		Kind:       "Policy",
+		SpecSchema: []byte(`{"type": "object", "properties": {"name": {"type": "string"}}}`),
+		Served:     true,
+		Storage:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dat, err := Marshal(obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: policies.example.org
+spec:
+  group: example.org
+  names:
+    kind: Policy
+    listKind: PolicyList
+    plural: policies
+    singular: policy
+  scope: Namespaced
+  versions:
+  - name: v1alpha1
+    schema:
+      openAPIV3Schema:
+        properties:
+          apiVersion:
+            description: |-
+              APIVersion defines the versioned schema of this representation of an object.
+              Servers should convert recognized schemas to the latest internal value, and
+              may reject unrecognized values.
+              More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources
+            type: string
+          kind:
+            description: |-
+              Kind is a string value representing the REST resource this object represents.
+              Servers may infer this from the endpoint the client submits requests to.
+              Cannot be updated.
+              In CamelCase.
+              More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds
+            type: string
+          metadata:
+            type: object
+          spec:
+            properties:
+              name:
+                type: string
+            type: object
+        type: object
+    served: true
+    storage: true
+`
+	if got := string(dat); got != want {
+		t.Errorf("unexpected manifest:\n%s", got)
+	}
+}
@@ -0,0 +1,54 @@
+package crd
+
+import (
+	"bytes"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// Object converts the CustomResourceDefinition to its unstructured
+// form, without the fields owned by the API server.
+func Object(obj *apiextensionsv1.CustomResourceDefinition) (map[string]any, error) {
+	dict, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	// status and creationTimestamp are owned by the API server
+	delete(dict, "status")
+	if meta, ok := dict["metadata"].(map[string]any); ok {
+		delete(meta, "creationTimestamp")
+	}
+
+	return dict, nil
+}
+
+// Marshal encodes the CustomResourceDefinition as a YAML document
+// laid out like the manifests produced by controller-gen.
+func Marshal(obj *apiextensionsv1.CustomResourceDefinition) ([]byte, error) {
+	dict, err := Object(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	dat, err := yaml.Marshal(dict)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.NewBufferString("---\n")
+	buf.Write(dat)
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decodes a YAML or JSON CustomResourceDefinition manifest.
+func Unmarshal(data []byte) (*apiextensionsv1.CustomResourceDefinition, error) {
+	obj := &apiextensionsv1.CustomResourceDefinition{}
+	if err := yaml.Unmarshal(data, obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
@@ -0,0 +1,268 @@
+package crd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/krateoplatformops/crdgen/internal/ptr"
+	"github.com/krateoplatformops/crdgen/internal/transpiler"
+	"github.com/krateoplatformops/crdgen/internal/transpiler/jsonschema"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+const (
+	apiVersionDescription = `APIVersion defines the versioned schema of this representation of an object.
+Servers should convert recognized schemas to the latest internal value, and
+may reject unrecognized values.
+More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#resources`
+
+	kindDescription = `Kind is a string value representing the REST resource this object represents.
+Servers may infer this from the endpoint the client submits requests to.
+Cannot be updated.
+In CamelCase.
+More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#types-kinds`
+)
+
+func transpile(data []byte) (map[string]transpiler.Struct, error) {
+	schema, err := jsonschema.ParseReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	return transpiler.Transpile(schema)
+}
+
+// schemaBuilder turns the transpiled model into OpenAPI v3 schemas
+// applying the same semantics controller-gen gives to the markers
+// rendered by the coder package.
+type schemaBuilder struct {
+	structs map[string]transpiler.Struct
+	// structs currently being expanded, used to detect recursive types
+	visiting map[string]bool
+}
+
+func newSchemaBuilder(structs map[string]transpiler.Struct) *schemaBuilder {
+	return &schemaBuilder{
+		structs:  structs,
+		visiting: make(map[string]bool),
+	}
+}
+
+func (b *schemaBuilder) object(el transpiler.Struct) (apiextensionsv1.JSONSchemaProps, error) {
+	res := apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+	}
+
+	if el.PreserveUnknownFields {
+		res.XPreserveUnknownFields = ptr.To(true)
+	}
+
+	for _, f := range el.Fields {
+		// a typed additionalProperties alongside regular properties
+		// cannot be expressed by a structural schema.
+		if f.JSONName == "" {
+			res.XPreserveUnknownFields = ptr.To(true)
+			continue
+		}
+
+		prop, err := b.field(f)
+		if err != nil {
+			return res, err
+		}
+
+		if res.Properties == nil {
+			res.Properties = make(map[string]apiextensionsv1.JSONSchemaProps, len(el.Fields))
+		}
+		res.Properties[f.JSONName] = prop
+
+		if f.Required {
+			res.Required = append(res.Required, f.JSONName)
+		}
+	}
+	sort.Strings(res.Required)
+
+	return res, nil
+}
+
+func (b *schemaBuilder) field(el transpiler.Field) (apiextensionsv1.JSONSchemaProps, error) {
+	res, err := b.typeOf(el.Type)
+	if err != nil {
+		return res, fmt.Errorf("field '%s': %w", el.JSONName, err)
+	}
+
+	res.Description = el.Description
+	res.Title = el.Title
+
+	if el.Default != nil {
+		val, err := defaultValue(el.Type, el.Default)
+		if err != nil {
+			return res, fmt.Errorf("field '%s': invalid default: %w", el.JSONName, err)
+		}
+		res.Default = &val
+	}
+
+	if el.Minimum != nil {
+		res.Minimum = ptr.To(*el.Minimum)
+	}
+
+	if el.Maximum != nil {
+		res.Maximum = ptr.To(*el.Maximum)
+	}
+
+	if el.MultipleOf != nil {
+		res.MultipleOf = ptr.To(*el.MultipleOf)
+	}
+
+	if el.Pattern != nil {
+		res.Pattern = *el.Pattern
+	}
+
+	if len(el.Enum) > 0 {
+		all := make([]apiextensionsv1.JSON, 0, len(el.Enum))
+		for _, x := range el.Enum {
+			all = append(all, enumValue(x))
+		}
+
+		// enum on array fields constrains the items
+		if res.Type == "array" && res.Items != nil && res.Items.Schema != nil {
+			res.Items.Schema.Enum = all
+		} else {
+			res.Enum = all
+		}
+	}
+
+	return res, nil
+}
+
+func (b *schemaBuilder) typeOf(typ string) (apiextensionsv1.JSONSchemaProps, error) {
+	typ = strings.TrimPrefix(typ, "*")
+
+	if sub, ok := strings.CutPrefix(typ, "[]"); ok {
+		items, err := b.typeOf(sub)
+		if err != nil {
+			return items, err
+		}
+
+		return apiextensionsv1.JSONSchemaProps{
+			Type:  "array",
+			Items: &apiextensionsv1.JSONSchemaPropsOrArray{Schema: &items},
+		}, nil
+	}
+
+	if sub, ok := strings.CutPrefix(typ, "map[string]"); ok {
+		values, err := b.typeOf(sub)
+		if err != nil {
+			return values, err
+		}
+
+		return apiextensionsv1.JSONSchemaProps{
+			Type: "object",
+			AdditionalProperties: &apiextensionsv1.JSONSchemaPropsOrBool{
+				Allows: true,
+				Schema: &values,
+			},
+		}, nil
+	}
+
+	switch typ {
+	case "string":
+		return apiextensionsv1.JSONSchemaProps{Type: "string"}, nil
+	case "bool":
+		return apiextensionsv1.JSONSchemaProps{Type: "boolean"}, nil
+	case "int":
+		return apiextensionsv1.JSONSchemaProps{Type: "integer"}, nil
+	case "float64":
+		return apiextensionsv1.JSONSchemaProps{Type: "number"}, nil
+	case "any", "interface{}", "nil":
+		return apiextensionsv1.JSONSchemaProps{XPreserveUnknownFields: ptr.To(true)}, nil
+	}
+
+	el, ok := b.structs[typ]
+	if !ok {
+		return apiextensionsv1.JSONSchemaProps{}, fmt.Errorf("unknown type '%s'", typ)
+	}
+
+	if b.visiting[typ] {
+		return apiextensionsv1.JSONSchemaProps{}, fmt.Errorf("recursive type '%s'", typ)
+	}
+	b.visiting[typ] = true
+	defer delete(b.visiting, typ)
+
+	return b.object(el)
+}
+
+// defaultValue converts the default of the transpiled model in a JSON value;
+// like the default marker, strings are taken verbatim for non string fields.
+func defaultValue(typ string, val any) (apiextensionsv1.JSON, error) {
+	if s, ok := val.(string); ok && strings.TrimPrefix(typ, "*") != "string" {
+		if json.Valid([]byte(s)) {
+			return apiextensionsv1.JSON{Raw: []byte(s)}, nil
+		}
+	}
+
+	dat, err := json.Marshal(val)
+	return apiextensionsv1.JSON{Raw: dat}, err
+}
+
+// enumValue converts an enum entry of the transpiled model
+// (quoted for strings, verbatim otherwise) in a JSON value.
+func enumValue(s string) apiextensionsv1.JSON {
+	if json.Valid([]byte(s)) {
+		return apiextensionsv1.JSON{Raw: []byte(s)}
+	}
+
+	dat, _ := json.Marshal(s)
+	return apiextensionsv1.JSON{Raw: dat}
+}
+
+func conditionsSchema() apiextensionsv1.JSONSchemaProps {
+	return apiextensionsv1.JSONSchemaProps{
+		Description: "Conditions of the resource.",
+		Type:        "array",
+		Items: &apiextensionsv1.JSONSchemaPropsOrArray{
+			Schema: &apiextensionsv1.JSONSchemaProps{
+				Description: "A Condition that may apply to a resource.",
+				Type:        "object",
+				Properties: map[string]apiextensionsv1.JSONSchemaProps{
+					"lastTransitionTime": {
+						Description: "LastTransitionTime is the last time this condition transitioned from one\nstatus to another.",
+						Type:        "string",
+						Format:      "date-time",
+					},
+					"message": {
+						Description: "A Message containing details about this condition's last transition from\none status to another, if any.",
+						Type:        "string",
+					},
+					"reason": {
+						Description: "A Reason for this condition's last transition from one status to another.",
+						Type:        "string",
+					},
+					"status": {
+						Description: "Status of this condition; is it currently True, False, or Unknown?",
+						Type:        "string",
+					},
+					"type": {
+						Description: "Type of this condition. At most one of each condition type may apply to\na resource at any point in time.",
+						Type:        "string",
+					},
+				},
+				Required: []string{"lastTransitionTime", "reason", "status", "type"},
+			},
+		},
+	}
+}
+
+func failedObjectRefSchema() apiextensionsv1.JSONSchemaProps {
+	return apiextensionsv1.JSONSchemaProps{
+		Type: "object",
+		Properties: map[string]apiextensionsv1.JSONSchemaProps{
+			"apiVersion": {Description: "API version of the object.", Type: "string"},
+			"kind":       {Description: "Kind of the object.", Type: "string"},
+			"name":       {Description: "Name of the object.", Type: "string"},
+			"namespace":  {Description: "Namespace of the object.", Type: "string"},
+		},
+	}
+}
@@ -0,0 +1,14 @@
+package inflect
+
+import (
+	"strings"
+
+	"github.com/gobuffalo/flect"
+)
+
+// Pluralize returns the lowercase plural form of a kind, e.g. "Policy" => "policies".
+// It matches the default plural of controller-gen, which the names of the CRDs
+// generated so far depend on, e.g. "Repo" => "repoes".
+func Pluralize(kind string) string {
+	return strings.ToLower(flect.Pluralize(kind))
+}
@@ -0,0 +1,45 @@
+package inflect_test
+
+import (
+	"testing"
+
+	"github.com/krateoplatformops/crdgen/internal/inflect"
+)
+
+func TestPluralize(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{input: "Xapp", expected: "xapps"},
+		{input: "Policy", expected: "policies"},
+		{input: "Proxy", expected: "proxies"},
+		{input: "Gateway", expected: "gateways"},
+		{input: "Status", expected: "statuses"},
+		{input: "Box", expected: "boxes"},
+		{input: "Patch", expected: "patches"},
+		{input: "Mesh", expected: "meshes"},
+		{input: "Person", expected: "people"},
+		{input: "Index", expected: "indices"},
+		{input: "Hero", expected: "heroes"},
+		{input: "Repo", expected: "repoes"},
+		{input: "Cactus", expected: "cacti"},
+		{input: "Bounds", expected: "bounds"},
+		{input: "Data", expected: "data"},
+		{input: "Metadata", expected: "metadata"},
+		{input: "DatabaseProxy", expected: "databaseproxies"},
+		{input: "NetworkPolicy", expected: "networkpolicies"},
+		{input: "HTTPProxy", expected: "httpproxies"},
+		{input: "ClusterPerson", expected: "clusterpeople"},
+		{input: "ManifestIndex", expected: "manifestindices"},
+		{input: "Human", expected: "humans"},
+		{input: "DNS", expected: "dnses"},
+		{input: "", expected: ""},
+	}
+
+	for _, tc := range tests {
+		if got := inflect.Pluralize(tc.input); got != tc.expected {
+			t.Errorf("Pluralize(%q): expected %q, got %q", tc.input, tc.expected, got)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package transpiler
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -78,7 +79,7 @@ type transpiler struct {
 	anonCount int
 }
 
-func (g *transpiler) createField(name, rootType string, schema *jsonschema.Schema) Field {
+func (g *transpiler) createField(name, rootType string, schema *jsonschema.Schema) (Field, error) {
 	f := Field{
 		Name:     name,
 		JSONName: "",
@@ -109,6 +110,12 @@ func (g *transpiler) createField(name, rootType string, schema *jsonschema.Schem
 		f.MultipleOf = ptr.To(*schema.MultipleOf)
 	}
 
+	if ty, multiple := schema.Type(); !multiple && ty == "integer" {
+		if err := integerBounds(&f); err != nil {
+			return f, fmt.Errorf("field '%s': %w", name, err)
+		}
+	}
+
 	if schema.Enum != nil {
 		f.Enum = strslice(schema.Enum)
 
@@ -132,7 +139,22 @@ func (g *transpiler) createField(name, rootType string, schema *jsonschema.Schem
 		f.Pattern = ptr.To(*schema.Pattern)
 	}
 
-	return f
+	return f, nil
+}
+
+// integerBounds rounds the fractional bounds of an integer field to the
+// nearest integers they admit, as controller-gen rejects them.
+func integerBounds(f *Field) error {
+	if f.Minimum != nil {
+		f.Minimum = ptr.To(math.Ceil(*f.Minimum))
+	}
+	if f.Maximum != nil {
+		f.Maximum = ptr.To(math.Floor(*f.Maximum))
+	}
+	if f.MultipleOf != nil && *f.MultipleOf != math.Trunc(*f.MultipleOf) {
+		return fmt.Errorf("non-integral multipleOf %v of an integer", *f.MultipleOf)
+	}
+	return nil
 }
 
 // createStructs creates types from the JSON schemas, keyed by the golang name.
@@ -150,7 +172,10 @@ func (g *transpiler) createStructs() (err error) {
 		}
 		// ugh: if it was anything but a struct the type will not be the name...
 		if rootType != "*"+name {
-			f := g.createField(name, rootType, schema)
+			f, err := g.createField(name, rootType, schema)
+			if err != nil {
+				return err
+			}
 			g.Aliases[name] = f
 		}
 
@@ -295,7 +320,10 @@ func (g *transpiler) processObject(name string, schema *jsonschema.Schema) (typ
 			return "", err
 		}
 
-		f := g.createField(fieldName, fieldType, prop)
+		f, err := g.createField(fieldName, fieldType, prop)
+		if err != nil {
+			return "", err
+		}
 		f.JSONName = propKey
 		f.Required = contains(schema.Required, propKey)
 		if f.Required {
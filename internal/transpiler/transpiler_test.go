@@ -647,3 +647,35 @@ func contains(s []string, e string) bool {
 	}
 	return false
 }
+
+func TestIntegerBounds(t *testing.T) {
+	root := jsonschema.Schema{
+		TypeValue: "object",
+		Properties: map[string]*jsonschema.Schema{
+			"replicas": {TypeValue: "integer", Minimum: ptr.To(0.5), Maximum: ptr.To(4.5)},
+			"ratio":    {TypeValue: "number", Minimum: ptr.To(0.5), Maximum: ptr.To(4.5)},
+		},
+	}
+	root.Init()
+
+	structs, err := transpiler.Transpile(&root)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := structs["Root"].Fields
+	if got := *fields["Replicas"].Minimum; got != 1 {
+		t.Errorf("expected the integer minimum rounded up to 1, got %v", got)
+	}
+	if got := *fields["Replicas"].Maximum; got != 4 {
+		t.Errorf("expected the integer maximum rounded down to 4, got %v", got)
+	}
+	if got := *fields["Ratio"].Minimum; got != 0.5 {
+		t.Errorf("expected the number minimum kept, got %v", got)
+	}
+
+	root.Properties["replicas"].MultipleOf = ptr.To(0.5)
+	if _, err := transpiler.Transpile(&root); err == nil {
+		t.Errorf("expected an error for a non-integral multipleOf of an integer")
+	}
+}
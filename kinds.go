@@ -0,0 +1,123 @@
+package crdgen
+
+import (
+	"context"
+	"fmt"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+
+	"github.com/krateoplatformops/crdgen/internal/coder"
+)
+
+// Kind describes one of the kinds generated in the same Go module,
+// each kind resulting in its own CRD; the fields have the same
+// meaning of the Options ones.
+type Kind struct {
+	GVK                    schema.GroupVersionKind
+	Categories             []string
+	SpecJsonSchemaGetter   JsonSchemaGetter
+	StatusJsonSchemaGetter JsonSchemaGetter
+	Managed                bool
+	Versions               []Version
+	Scope                  apiextensionsv1.ResourceScope
+	Plural                 string
+	Singular               string
+	ShortNames             []string
+	ListKind               string
+	Conversion             *ConversionWebhook
+	// Labels and Annotations are added to the metadata of the CRD,
+	// overriding those of the Options.
+	Labels      map[string]string
+	Annotations map[string]string
+}
+
+// kinds returns the kinds requested by the options; when Kinds
+// is empty the single kind described by the options themselves.
+func (o *Options) kinds() []Kind {
+	if len(o.Kinds) > 0 {
+		return o.Kinds
+	}
+
+	return []Kind{{
+		GVK:                    o.GVK,
+		Categories:             o.Categories,
+		SpecJsonSchemaGetter:   o.SpecJsonSchemaGetter,
+		StatusJsonSchemaGetter: o.StatusJsonSchemaGetter,
+		Managed:                o.Managed,
+		Versions:               o.Versions,
+		Scope:                  o.Scope,
+		Plural:                 o.Plural,
+		Singular:               o.Singular,
+		ShortNames:             o.ShortNames,
+		ListKind:               o.ListKind,
+		Conversion:             o.Conversion,
+	}}
+}
+
+// crdPlan holds the resources resulting in a single CRD.
+type crdPlan struct {
+	kind    Kind
+	name    string
+	storage string
+	all     []*coder.Resource
+	// labels and annotations of the CRD metadata
+	labels      map[string]string
+	annotations map[string]string
+}
+
+// gvk returns the group, storage version and kind of the CRD.
+func (p *crdPlan) gvk() schema.GroupVersionKind {
+	return schema.GroupVersionKind{
+		Group:   p.kind.GVK.Group,
+		Version: p.storage,
+		Kind:    p.kind.GVK.Kind,
+	}
+}
+
+// plans validates the requested kinds fetching their JSON schemas.
+func plans(ctx context.Context, opts Options) ([]*crdPlan, error) {
+	kinds := opts.kinds()
+
+	ctx, cancel := withTimeout(ctx, opts.Timeouts.Fetch)
+	defer cancel()
+
+	res := make([]*crdPlan, 0, len(kinds))
+	seen := make(map[string]bool, len(kinds))
+	for _, k := range kinds {
+		p, err := resources(ctx, k, opts.Timeouts.Fetch)
+		if err != nil {
+			if len(kinds) > 1 {
+				return nil, fmt.Errorf("kind '%s': %w", k.GVK.Kind, err)
+			}
+			return nil, err
+		}
+
+		if seen[p.name] {
+			return nil, fmt.Errorf("duplicate CRD '%s'", p.name)
+		}
+		seen[p.name] = true
+
+		p.labels = mergeMetadata(opts.Labels, k.Labels)
+		p.annotations = mergeMetadata(opts.Annotations, k.Annotations)
+		if err := validateMetadata(k.GVK.Group, p.labels, p.annotations); err != nil {
+			if len(kinds) > 1 {
+				return nil, fmt.Errorf("kind '%s': %w", k.GVK.Kind, err)
+			}
+			return nil, err
+		}
+
+		res = append(res, p)
+	}
+
+	return res, nil
+}
+
+// resourcesOf returns the resources of all the plans.
+func resourcesOf(all []*crdPlan) []*coder.Resource {
+	res := []*coder.Resource{}
+	for _, p := range all {
+		res = append(res, p.all...)
+	}
+	return res
+}
@@ -0,0 +1,104 @@
+package crdgen_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestKinds(t *testing.T) {
+	res := crdgen.Generate(context.Background(), crdgen.Options{
+		Native: true,
+		Kinds: []crdgen.Kind{
+			{
+				GVK: schema.GroupVersionKind{
+					Group: "example.org", Version: "v1alpha1", Kind: "Database",
+				},
+				SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+			},
+			{
+				GVK: schema.GroupVersionKind{
+					Group: "example.org", Version: "v1alpha1", Kind: "Bucket",
+				},
+				Managed:              true,
+				SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+			},
+		},
+	})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	if len(res.Manifests) != 2 {
+		t.Fatalf("expected 2 manifests, got %d", len(res.Manifests))
+	}
+
+	for _, name := range []string{"databases.example.org", "buckets.example.org"} {
+		dat, ok := res.Manifests[name]
+		if !ok {
+			t.Fatalf("missing manifest of '%s'", name)
+		}
+
+		if !strings.Contains(string(dat), "name: "+name+"\n") {
+			t.Errorf("manifest of '%s' has the wrong name:\n%s", name, dat)
+		}
+
+		obj, ok := res.CRDs[name]
+		if !ok {
+			t.Fatalf("missing CRD '%s'", name)
+		}
+		if obj.Name != name {
+			t.Errorf("expected CRD '%s', got '%s'", name, obj.Name)
+		}
+	}
+
+	if got := res.CRDs["buckets.example.org"].Spec.Versions[0].Subresources; got == nil || got.Status == nil {
+		t.Errorf("expected the status subresource of the managed kind")
+	}
+
+	want := string(res.Manifests["databases.example.org"]) + string(res.Manifests["buckets.example.org"])
+	if string(res.Manifest) != want {
+		t.Errorf("expected the manifests in order of kind")
+	}
+
+	if res.GVK.Kind != "Database" {
+		t.Errorf("expected the GVK of the first kind, got %s", res.GVK)
+	}
+}
+
+func TestKindsErrors(t *testing.T) {
+	kind := func(name, plural string) crdgen.Kind {
+		return crdgen.Kind{
+			GVK: schema.GroupVersionKind{
+				Group: "example.org", Version: "v1alpha1", Kind: name,
+			},
+			Plural:               plural,
+			SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+		}
+	}
+
+	tests := map[string][]crdgen.Kind{
+		"duplicate kind":   {kind("Database", ""), kind("Database", "")},
+		"duplicate plural": {kind("Database", "stores"), kind("Bucket", "stores")},
+		"invalid kind":     {kind("Database", ""), kind("", "")},
+		"invalid scope": {func() crdgen.Kind {
+			k := kind("Tenant", "")
+			k.Scope = "Global"
+			return k
+		}()},
+	}
+
+	for name, kinds := range tests {
+		res := crdgen.Generate(context.Background(), crdgen.Options{
+			Native: true,
+			Kinds:  kinds,
+		})
+		if res.Err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
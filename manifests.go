@@ -0,0 +1,121 @@
+package crdgen
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/krateoplatformops/crdgen/internal/crd"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// readManifests reads every CRD manifest in the directory
+// returning them keyed by CRD name.
+func readManifests(fsys fs.FS, dir string) (map[string][]byte, error) {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, &ReadManifestError{File: dir, Err: err}
+	}
+
+	res := map[string][]byte{}
+	for _, el := range files {
+		if el.IsDir() || !strings.HasSuffix(el.Name(), ".yaml") {
+			continue
+		}
+
+		name := path.Join(dir, el.Name())
+
+		dat, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, &ReadManifestError{File: name, Err: err}
+		}
+
+		all, err := splitManifests(dat)
+		if err != nil {
+			return nil, &ReadManifestError{File: name, Err: err}
+		}
+
+		for k, v := range all {
+			if _, ok := res[k]; ok {
+				return nil, &ReadManifestError{File: name, Err: fmt.Errorf("duplicate CRD '%s'", k)}
+			}
+			res[k] = v
+		}
+	}
+
+	return res, nil
+}
+
+// splitManifests splits a multi-document YAML in CRD manifests
+// keyed by CRD name, skipping the documents of any other kind.
+func splitManifests(data []byte) (map[string][]byte, error) {
+	res := map[string][]byte{}
+
+	for _, doc := range splitDocuments(data) {
+		obj, err := crd.Unmarshal(doc)
+		if err != nil {
+			return nil, err
+		}
+
+		if obj.Kind != "CustomResourceDefinition" || len(obj.Name) == 0 {
+			continue
+		}
+
+		if _, ok := res[obj.Name]; ok {
+			return nil, fmt.Errorf("duplicate CRD '%s'", obj.Name)
+		}
+
+		res[obj.Name] = doc
+	}
+
+	return res, nil
+}
+
+// decodeManifests decodes the CRD manifests keyed by CRD name.
+func decodeManifests(all map[string][]byte) (map[string]*apiextensionsv1.CustomResourceDefinition, error) {
+	res := make(map[string]*apiextensionsv1.CustomResourceDefinition, len(all))
+	for name, dat := range all {
+		obj, err := crd.Unmarshal(dat)
+		if err != nil {
+			return nil, fmt.Errorf("decoding CRD '%s': %w", name, err)
+		}
+		res[name] = obj
+	}
+
+	return res, nil
+}
+
+// splitDocuments splits a multi-document YAML; every
+// document keeps its leading '---' separator.
+func splitDocuments(data []byte) [][]byte {
+	res := [][]byte{}
+
+	cur := bytes.Buffer{}
+	flush := func() {
+		if len(bytes.TrimSpace(bytes.TrimPrefix(cur.Bytes(), []byte("---\n")))) > 0 {
+			res = append(res, bytes.Clone(cur.Bytes()))
+		}
+		cur.Reset()
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for sc.Scan() {
+		line := sc.Text()
+		if strings.TrimRight(line, " \t") == "---" {
+			flush()
+		}
+
+		if cur.Len() == 0 && line != "---" {
+			cur.WriteString("---\n")
+		}
+		cur.WriteString(line)
+		cur.WriteByte('\n')
+	}
+	flush()
+
+	return res
+}
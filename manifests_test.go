@@ -0,0 +1,49 @@
+package crdgen
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestReadManifests(t *testing.T) {
+	fsys := fstest.MapFS{
+		"crds/example.org_xapps.yaml": {Data: []byte(`---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: xapps.example.org
+---
+apiVersion: rbac.authorization.k8s.io/v1
+kind: ClusterRole
+metadata:
+  name: xapps-editor
+`)},
+		"crds/example.org_buckets.yaml": {Data: []byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: buckets.example.org
+`)},
+		"crds/README.md":      {Data: []byte("# stray file\n")},
+		"crds/old/stale.yaml": {Data: []byte("kind: CustomResourceDefinition\nmetadata:\n  name: stale.example.org\n")},
+	}
+
+	res, err := readManifests(fsys, "crds")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res) != 2 {
+		t.Fatalf("expected 2 manifests, got %d: %v", len(res), res)
+	}
+
+	for _, name := range []string{"xapps.example.org", "buckets.example.org"} {
+		if _, ok := res[name]; !ok {
+			t.Errorf("missing manifest of '%s'", name)
+		}
+	}
+
+	fsys["crds/dup.yaml"] = fsys["crds/example.org_buckets.yaml"]
+	if _, err := readManifests(fsys, "crds"); err == nil {
+		t.Errorf("expected an error for a duplicate CRD")
+	}
+}
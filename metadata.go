@@ -0,0 +1,143 @@
+package crdgen
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"k8s.io/apiextensions-apiserver/pkg/apihelpers"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/validation"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Provenance annotations stamped on the generated CRDs.
+const (
+	// AnnotationVersion is the crdgen version, e.g. 'v0.5.0'.
+	AnnotationVersion = "crdgen.krateo.io/version"
+	// AnnotationDigest is the digest of the input, see Result.Digest.
+	AnnotationDigest = "crdgen.krateo.io/digest"
+	// AnnotationSource is the comma separated list of the
+	// locations of the JSON schemas.
+	AnnotationSource = "crdgen.krateo.io/source"
+	// AnnotationGeneratedAt is the generation time (RFC 3339, UTC).
+	AnnotationGeneratedAt = "crdgen.krateo.io/generated-at"
+)
+
+// JsonSchemaSource is implemented by the JsonSchemaGetters knowing
+// the location of their JSON schema, e.g. a file path or an URL.
+type JsonSchemaSource interface {
+	Source() string
+}
+
+// Provenance configures the annotations recording how each CRD
+// was generated: the crdgen version, the input digest, the source
+// of the JSON schemas and the generation time.
+type Provenance struct {
+	// Source is the location of the JSON schemas, by default the
+	// locations reported by the getters implementing JsonSchemaSource.
+	Source string
+	// OmitTimestamp leaves out the generation time so that the same
+	// input always results in the same manifests.
+	OmitTimestamp bool
+}
+
+// annotations returns the provenance annotations of the CRD.
+func (p *Provenance) annotations(plan *crdPlan, digest string, now time.Time) map[string]string {
+	res := map[string]string{
+		AnnotationVersion: moduleVersion(),
+		AnnotationDigest:  digest,
+	}
+
+	src := p.Source
+	if len(src) == 0 {
+		src = strings.Join(plan.sources(), ",")
+	}
+	if len(src) > 0 {
+		res[AnnotationSource] = src
+	}
+
+	if !p.OmitTimestamp {
+		res[AnnotationGeneratedAt] = now.UTC().Format(time.RFC3339)
+	}
+
+	return res
+}
+
+// sources returns the distinct locations of the JSON schemas of the
+// CRD, in order of version; the getters without location are skipped.
+func (p *crdPlan) sources() []string {
+	res := []string{}
+	for _, v := range p.kind.versions() {
+		for _, g := range []JsonSchemaGetter{v.SpecJsonSchemaGetter, v.StatusJsonSchemaGetter} {
+			src, ok := g.(JsonSchemaSource)
+			if !ok {
+				continue
+			}
+			if el := src.Source(); len(el) > 0 && !slices.Contains(res, el) {
+				res = append(res, el)
+			}
+		}
+	}
+	return res
+}
+
+// mergeMetadata returns the labels or the annotations of all
+// the CRDs completed, or overridden, by the ones of a kind.
+func mergeMetadata(all, kind map[string]string) map[string]string {
+	if len(all) == 0 && len(kind) == 0 {
+		return nil
+	}
+
+	res := make(map[string]string, len(all)+len(kind))
+	maps.Copy(res, all)
+	maps.Copy(res, kind)
+	return res
+}
+
+// validateMetadata checks the labels and the annotations of the CRD
+// of the group; the groups protected by the Kubernetes community
+// require the API approval annotation.
+func validateMetadata(group string, labels, annotations map[string]string) error {
+	pth := field.NewPath("metadata")
+
+	errs := metav1validation.ValidateLabels(labels, pth.Child("labels"))
+	errs = append(errs, validation.ValidateAnnotations(annotations, pth.Child("annotations"))...)
+	if len(errs) > 0 {
+		return errs.ToAggregate()
+	}
+
+	if apihelpers.IsProtectedCommunityGroup(group) {
+		state, reason := apihelpers.GetAPIApprovalState(annotations)
+		switch state {
+		case apihelpers.APIApproved, apihelpers.APIApprovalBypassed:
+		default:
+			return fmt.Errorf("annotation '%s' of group '%s': %s", apiextensionsv1.KubeAPIApprovedAnnotation, group, reason)
+		}
+	}
+
+	return nil
+}
+
+// withAnnotations adds the annotations to the CRD.
+func withAnnotations(annotations map[string]string) patchFunc {
+	return func(obj *apiextensionsv1.CustomResourceDefinition) {
+		if obj.Annotations == nil {
+			obj.Annotations = make(map[string]string, len(annotations))
+		}
+		maps.Copy(obj.Annotations, annotations)
+	}
+}
+
+// withLabels adds the labels to the CRD.
+func withLabels(labels map[string]string) patchFunc {
+	return func(obj *apiextensionsv1.CustomResourceDefinition) {
+		if obj.Labels == nil {
+			obj.Labels = make(map[string]string, len(labels))
+		}
+		maps.Copy(obj.Labels, labels)
+	}
+}
@@ -0,0 +1,180 @@
+package crdgen_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/cache"
+	"github.com/krateoplatformops/crdgen/getter"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestMetadata(t *testing.T) {
+	res := crdgen.Generate(context.Background(), crdgen.Options{
+		Native: true,
+		Labels: map[string]string{
+			"app.kubernetes.io/managed-by": "crdgen",
+			"krateo.io/team":               "platform",
+		},
+		Annotations: map[string]string{
+			"krateo.io/owner": "platform@example.org",
+		},
+		Kinds: []crdgen.Kind{
+			{
+				GVK: schema.GroupVersionKind{
+					Group: "example.org", Version: "v1alpha1", Kind: "Database",
+				},
+				SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+				Labels: map[string]string{
+					"krateo.io/team": "data",
+				},
+			},
+			{
+				GVK: schema.GroupVersionKind{
+					Group: "example.org", Version: "v1alpha1", Kind: "Bucket",
+				},
+				SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+			},
+		},
+	})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	db := res.CRDs["databases.example.org"]
+	if got := db.Labels["krateo.io/team"]; got != "data" {
+		t.Errorf("expected the label of the kind, got '%s'", got)
+	}
+	if got := db.Labels["app.kubernetes.io/managed-by"]; got != "crdgen" {
+		t.Errorf("expected the label of the options, got '%s'", got)
+	}
+
+	bucket := res.CRDs["buckets.example.org"]
+	if got := bucket.Labels["krateo.io/team"]; got != "platform" {
+		t.Errorf("expected the label of the options, got '%s'", got)
+	}
+	if got := bucket.Annotations["krateo.io/owner"]; got != "platform@example.org" {
+		t.Errorf("expected the annotation of the options, got '%s'", got)
+	}
+
+	if _, ok := bucket.Annotations[crdgen.AnnotationDigest]; ok {
+		t.Errorf("unexpected provenance annotations")
+	}
+}
+
+func TestMetadataErrors(t *testing.T) {
+	tests := map[string]crdgen.Options{
+		"invalid label": {
+			GVK:    schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "Xapp"},
+			Labels: map[string]string{"team": "not a value"},
+		},
+		"invalid annotation": {
+			GVK:         schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "Xapp"},
+			Annotations: map[string]string{"-owner": "me"},
+		},
+		"missing api approval": {
+			GVK: schema.GroupVersionKind{Group: "demo.k8s.io", Version: "v1alpha1", Kind: "Xapp"},
+		},
+		"invalid api approval": {
+			GVK:         schema.GroupVersionKind{Group: "demo.kubernetes.io", Version: "v1alpha1", Kind: "Xapp"},
+			Annotations: map[string]string{"api-approved.kubernetes.io": "yes"},
+		},
+	}
+
+	for name, opts := range tests {
+		opts.Native = true
+		opts.SpecJsonSchemaGetter = getter.Bytes(`{"type": "object"}`)
+
+		if res := crdgen.Generate(context.Background(), opts); res.Err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	res := crdgen.Generate(context.Background(), crdgen.Options{
+		GVK:    schema.GroupVersionKind{Group: "demo.k8s.io", Version: "v1alpha1", Kind: "Xapp"},
+		Native: true,
+		Annotations: map[string]string{
+			"api-approved.kubernetes.io": "https://github.com/kubernetes/enhancements/pull/1111",
+		},
+		SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+	})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if len(res.Violations) > 0 {
+		t.Errorf("unexpected violations: %v", res.Violations)
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "spec.json")
+	if err := os.WriteFile(file, []byte(`{"type": "object"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := crdgen.Options{
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Xapp",
+		},
+		Native:               true,
+		Cache:                cache.NewLRU(8),
+		SpecJsonSchemaGetter: getter.File(file),
+		Provenance:           &crdgen.Provenance{},
+	}
+
+	first := crdgen.Generate(context.Background(), opts)
+	if first.Err != nil {
+		t.Fatal(first.Err)
+	}
+
+	got := first.CRDs["xapps.example.org"].Annotations
+	if got[crdgen.AnnotationDigest] != first.Digest {
+		t.Errorf("expected digest '%s', got '%s'", first.Digest, got[crdgen.AnnotationDigest])
+	}
+	if len(got[crdgen.AnnotationVersion]) == 0 {
+		t.Errorf("missing the crdgen version")
+	}
+	if got[crdgen.AnnotationSource] != file {
+		t.Errorf("expected source '%s', got '%s'", file, got[crdgen.AnnotationSource])
+	}
+	if _, err := time.Parse(time.RFC3339, got[crdgen.AnnotationGeneratedAt]); err != nil {
+		t.Errorf("invalid generation time: %v", err)
+	}
+
+	// the cached manifest is stamped again
+	opts.Provenance = &crdgen.Provenance{Source: "https://example.org/xapp.json", OmitTimestamp: true}
+
+	second := crdgen.Generate(context.Background(), opts)
+	if second.Err != nil {
+		t.Fatal(second.Err)
+	}
+	if !second.CacheHit {
+		t.Fatalf("expected a cache hit")
+	}
+
+	got = second.CRDs["xapps.example.org"].Annotations
+	if got[crdgen.AnnotationSource] != "https://example.org/xapp.json" {
+		t.Errorf("expected the source of the options, got '%s'", got[crdgen.AnnotationSource])
+	}
+	if _, ok := got[crdgen.AnnotationGeneratedAt]; ok {
+		t.Errorf("unexpected generation time")
+	}
+
+	third := crdgen.Generate(context.Background(), opts)
+	if third.Err != nil {
+		t.Fatal(third.Err)
+	}
+	if !bytes.Equal(second.Manifest, third.Manifest) {
+		t.Errorf("expected the same manifest without timestamp")
+	}
+	if !bytes.Equal(second.Manifest, second.Manifests["xapps.example.org"]) {
+		t.Errorf("expected the stamped manifest")
+	}
+}
@@ -0,0 +1,165 @@
+package crdgen
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/krateoplatformops/crdgen/internal/assets"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/semver"
+)
+
+// Dependencies pins the versions required by the generated Go
+// module; the empty fields keep the default versions.
+type Dependencies struct {
+	// Go is the go directive, by default '1.24.0'.
+	Go string
+	// ProviderRuntime is the version of
+	// github.com/krateoplatformops/provider-runtime, by default 'v0.9.1'.
+	ProviderRuntime string
+	// Apimachinery is the version of k8s.io/apimachinery,
+	// by default 'v0.33.0'.
+	Apimachinery string
+	// ControllerRuntime is the version of sigs.k8s.io/controller-runtime,
+	// by default 'v0.20.0'.
+	ControllerRuntime string
+	// ControllerTools is the version of sigs.k8s.io/controller-tools,
+	// providing controller-gen, by default 'v0.18.0'.
+	ControllerTools string
+}
+
+var defaultDependencies = Dependencies{
+	Go:                "1.24.0",
+	ProviderRuntime:   "v0.9.1",
+	Apimachinery:      "v0.33.0",
+	ControllerRuntime: "v0.20.0",
+	ControllerTools:   "v0.18.0",
+}
+
+// withDefaults returns the dependencies completed with the default versions.
+func (d Dependencies) withDefaults() Dependencies {
+	def := func(v, fallback string) string {
+		if len(v) == 0 {
+			return fallback
+		}
+		return v
+	}
+
+	return Dependencies{
+		Go:                def(d.Go, defaultDependencies.Go),
+		ProviderRuntime:   def(d.ProviderRuntime, defaultDependencies.ProviderRuntime),
+		Apimachinery:      def(d.Apimachinery, defaultDependencies.Apimachinery),
+		ControllerRuntime: def(d.ControllerRuntime, defaultDependencies.ControllerRuntime),
+		ControllerTools:   def(d.ControllerTools, defaultDependencies.ControllerTools),
+	}
+}
+
+// validate checks the go directive and the module versions.
+func (d Dependencies) validate() error {
+	if len(d.Go) > 0 && !modfile.GoVersionRE.MatchString(d.Go) {
+		return fmt.Errorf("invalid go version '%s'", d.Go)
+	}
+
+	for name, v := range map[string]string{
+		"provider-runtime":   d.ProviderRuntime,
+		"apimachinery":       d.Apimachinery,
+		"controller-runtime": d.ControllerRuntime,
+		"controller-tools":   d.ControllerTools,
+	} {
+		if len(v) > 0 && !semver.IsValid(v) {
+			return fmt.Errorf("invalid %s version '%s'", name, v)
+		}
+	}
+
+	return nil
+}
+
+// data returns the data of the go.mod template.
+func (d Dependencies) data(module string) map[string]string {
+	return map[string]string{
+		"module":            module,
+		"go":                d.Go,
+		"providerRuntime":   d.ProviderRuntime,
+		"apimachinery":      d.Apimachinery,
+		"controllerRuntime": d.ControllerRuntime,
+		"controllerTools":   d.ControllerTools,
+	}
+}
+
+// Toolchain configures the go commands run in controller-gen mode.
+type Toolchain struct {
+	// Go is the path of the go binary, by default 'go' from the PATH.
+	Go string
+	// Env holds the 'KEY=value' variables added to the environment
+	// of the go commands, e.g. 'GOPROXY=https://proxy.example.org';
+	// they win over the ones set by Offline.
+	Env []string
+}
+
+// command returns the go binary.
+func (t Toolchain) command() string {
+	if len(t.Go) == 0 {
+		return "go"
+	}
+	return t.Go
+}
+
+// environ returns the environment of the go commands: the base
+// one (nil means the current one) followed by Env.
+func (t Toolchain) environ(base []string) []string {
+	if len(t.Env) == 0 {
+		return base
+	}
+	if base == nil {
+		base = os.Environ()
+	}
+	return append(base[:len(base):len(base)], t.Env...)
+}
+
+// validate checks the environment variables.
+func (t Toolchain) validate() error {
+	for _, el := range t.Env {
+		if k, _, ok := strings.Cut(el, "="); !ok || len(k) == 0 {
+			return fmt.Errorf("invalid toolchain environment variable '%s': expected KEY=value", el)
+		}
+	}
+	return nil
+}
+
+// overriddenTemplates returns the SHA-256 of the templates
+// replaced by the overlay keyed by file name.
+func overriddenTemplates(overlay fs.FS) (map[string]string, error) {
+	if overlay == nil {
+		return nil, nil
+	}
+
+	res := map[string]string{}
+	for _, name := range assets.Names() {
+		if _, err := fs.Stat(overlay, name); err != nil {
+			continue
+		}
+
+		dat, err := assets.Read(overlay, name)
+		if err != nil {
+			return nil, err
+		}
+		res[name] = fmt.Sprintf("%x", sha256.Sum256(dat))
+	}
+
+	return res, nil
+}
+
+// validateBoilerplate checks that the boilerplate is made of Go comments.
+func validateBoilerplate(s string) error {
+	for _, line := range strings.Split(strings.TrimSpace(s), "\n") {
+		line = strings.TrimSpace(line)
+		if len(line) > 0 && !strings.HasPrefix(line, "//") && !strings.HasPrefix(line, "/*") &&
+			!strings.HasPrefix(line, "*") {
+			return fmt.Errorf("'%s' is not a Go comment", line)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package crdgen_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestToolchain(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake go binary is a shell script")
+	}
+
+	dir := t.TempDir()
+
+	// the fake go binary dumps the generated module and fails
+	gobin := filepath.Join(dir, "go")
+	script := "#!/bin/sh\ncat go.mod hack/boilerplate.go.txt > \"$CRDGEN_DUMP\"\nexit 1\n"
+	if err := os.WriteFile(gobin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	dump := filepath.Join(dir, "dump.txt")
+
+	res := crdgen.Generate(context.Background(), crdgen.Options{
+		WorkDir: "toolchain",
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Xapp",
+		},
+		SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+		Dependencies: crdgen.Dependencies{
+			Go:              "1.25.0",
+			ControllerTools: "v0.19.0",
+		},
+		Boilerplate: "// Copyright 2025 Example Org.",
+		Toolchain: crdgen.Toolchain{
+			Go:  gobin,
+			Env: []string{"CRDGEN_DUMP=" + dump},
+		},
+		Templates: fstest.MapFS{
+			"go.mod.tpl": &fstest.MapFile{
+				Data: []byte("module {{ .module }}\n\ngo {{ .go }}\n\n// tools {{ .controllerTools }}\n"),
+			},
+		},
+	})
+
+	var tidyErr *crdgen.TidyError
+	if !errors.As(res.Err, &tidyErr) {
+		t.Fatalf("expected a tidy error, got: %v", res.Err)
+	}
+
+	dat, err := os.ReadFile(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"go 1.25.0\n", "// tools v0.19.0\n", "// Copyright 2025 Example Org."} {
+		if !strings.Contains(string(dat), want) {
+			t.Errorf("expected %q in the generated module, got:\n%s", want, dat)
+		}
+	}
+}
+
+func TestModuleDigest(t *testing.T) {
+	base := func() crdgen.Options {
+		return crdgen.Options{
+			GVK: schema.GroupVersionKind{
+				Group:   "example.org",
+				Version: "v1alpha1",
+				Kind:    "Xapp",
+			},
+			SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+			// the digest is computed before running the toolchain
+			Toolchain: crdgen.Toolchain{Go: filepath.Join(t.TempDir(), "missing")},
+		}
+	}
+
+	digest := func(opts crdgen.Options) string {
+		res := crdgen.Generate(context.Background(), opts)
+		if len(res.Digest) == 0 {
+			t.Fatalf("missing digest: %v", res.Err)
+		}
+		return res.Digest
+	}
+
+	want := digest(base())
+
+	same := map[string]func(*crdgen.Options){
+		"default dependencies": func(o *crdgen.Options) { o.Dependencies.ControllerTools = "v0.18.0" },
+		"boilerplate":          func(o *crdgen.Options) { o.Boilerplate = "// Copyright" },
+		"other templates":      func(o *crdgen.Options) { o.Templates = fstest.MapFS{"other.tpl": {}} },
+		"skip deepcopy":        func(o *crdgen.Options) { o.ControllerGen.SkipDeepCopy = true },
+	}
+	for name, fn := range same {
+		opts := base()
+		fn(&opts)
+		if got := digest(opts); got != want {
+			t.Errorf("%s: expected the digest not to change", name)
+		}
+	}
+
+	changed := map[string]func(*crdgen.Options){
+		"dependencies": func(o *crdgen.Options) { o.Dependencies.ControllerTools = "v0.19.0" },
+		"crd options":  func(o *crdgen.Options) { o.ControllerGen.AllowDangerousTypes = true },
+		"templates": func(o *crdgen.Options) {
+			o.Templates = fstest.MapFS{"go.mod.tpl": {Data: []byte("module {{ .module }}\n")}}
+		},
+	}
+	for name, fn := range changed {
+		opts := base()
+		fn(&opts)
+		if got := digest(opts); got == want {
+			t.Errorf("%s: expected the digest to change", name)
+		}
+	}
+}
+
+func TestModuleErrors(t *testing.T) {
+	tests := map[string]func(*crdgen.Options){
+		"go version":     func(o *crdgen.Options) { o.Dependencies.Go = "go1.24" },
+		"module version": func(o *crdgen.Options) { o.Dependencies.Apimachinery = "0.33.0" },
+		"boilerplate":    func(o *crdgen.Options) { o.Boilerplate = "Copyright 2025" },
+		"environment":    func(o *crdgen.Options) { o.Toolchain.Env = []string{"GOPROXY"} },
+		"invalid template": func(o *crdgen.Options) {
+			o.Templates = fstest.MapFS{"go.mod.tpl": {Data: []byte("module {{ .module ")}}
+		},
+		"unknown template key": func(o *crdgen.Options) {
+			o.Templates = fstest.MapFS{"go.mod.tpl": {Data: []byte("module {{ .name }}\n")}}
+		},
+	}
+
+	for name, fn := range tests {
+		opts := crdgen.Options{
+			WorkDir: "errors",
+			GVK: schema.GroupVersionKind{
+				Group:   "example.org",
+				Version: "v1alpha1",
+				Kind:    "Xapp",
+			},
+			SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+			Toolchain:            crdgen.Toolchain{Go: filepath.Join(t.TempDir(), "missing")},
+		}
+		fn(&opts)
+
+		res := crdgen.Generate(context.Background(), opts)
+		if res.Err == nil {
+			t.Errorf("%s: expected an error", name)
+			continue
+		}
+
+		var tidyErr *crdgen.TidyError
+		if errors.As(res.Err, &tidyErr) {
+			t.Errorf("%s: expected an error before running the toolchain, got: %v", name, res.Err)
+		}
+	}
+}
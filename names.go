@@ -0,0 +1,90 @@
+package crdgen
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/krateoplatformops/crdgen/internal/inflect"
+	"github.com/krateoplatformops/crdgen/internal/strutil"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// names holds the resolved resource names of the CRD.
+type names struct {
+	plural     string
+	singular   string
+	shortNames []string
+	listKind   string
+}
+
+// resolveNames defaults the resource names not set in the options
+// and validates all of them.
+func resolveNames(k Kind) (res names, err error) {
+	kind := k.GVK.Kind
+	if kind == "" {
+		return res, fmt.Errorf("missing kind")
+	}
+
+	if err := validateLabel("kind", strings.ToLower(kind)); err != nil {
+		return res, err
+	}
+
+	res = names{
+		plural:     k.Plural,
+		singular:   k.Singular,
+		shortNames: k.ShortNames,
+		listKind:   k.ListKind,
+	}
+
+	if res.plural == "" {
+		res.plural = inflect.Pluralize(kind)
+	}
+
+	if res.singular == "" {
+		res.singular = strings.ToLower(kind)
+	}
+
+	if res.listKind == "" {
+		res.listKind = fmt.Sprintf("%sList", strutil.ToGolangName(kind))
+	}
+
+	if err := validateLabel("plural", res.plural); err != nil {
+		return res, err
+	}
+
+	if err := validateLabel("singular", res.singular); err != nil {
+		return res, err
+	}
+
+	for _, el := range res.shortNames {
+		if err := validateLabel("short name", el); err != nil {
+			return res, err
+		}
+	}
+
+	if strutil.ToGolangName(res.listKind) != res.listKind {
+		return res, fmt.Errorf("invalid list kind '%s': must be a CamelCase identifier", res.listKind)
+	}
+
+	if res.listKind == kind {
+		return res, fmt.Errorf("list kind '%s' must differ from kind", res.listKind)
+	}
+
+	if err := validateLabel("list kind", strings.ToLower(res.listKind)); err != nil {
+		return res, err
+	}
+
+	name := fmt.Sprintf("%s.%s", res.plural, k.GVK.Group)
+	if errs := validation.IsDNS1123Subdomain(name); len(errs) > 0 {
+		return res, fmt.Errorf("invalid CRD name '%s': %s", name, strings.Join(errs, ", "))
+	}
+
+	return res, nil
+}
+
+func validateLabel(what, val string) error {
+	if errs := validation.IsDNS1035Label(val); len(errs) > 0 {
+		return fmt.Errorf("invalid %s '%s': %s", what, val, strings.Join(errs, ", "))
+	}
+	return nil
+}
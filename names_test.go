@@ -0,0 +1,65 @@
+package crdgen_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestNames(t *testing.T) {
+	res := crdgen.Generate(context.TODO(), crdgen.Options{
+		Native: true,
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Proxy",
+		},
+		ShortNames:           []string{"px"},
+		ListKind:             "ProxyCollection",
+		SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+	})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	for _, want := range []string{
+		"name: proxies.example.org",
+		"plural: proxies",
+		"singular: proxy",
+		"listKind: ProxyCollection",
+		"- px",
+	} {
+		if !strings.Contains(string(res.Manifest), want) {
+			t.Errorf("expected %q in manifest:\n%s", want, res.Manifest)
+		}
+	}
+}
+
+func TestInvalidNames(t *testing.T) {
+	tests := []struct {
+		name string
+		opts crdgen.Options
+	}{
+		{name: "plural", opts: crdgen.Options{Plural: "Proxies"}},
+		{name: "singular", opts: crdgen.Options{Singular: "a_proxy"}},
+		{name: "short name", opts: crdgen.Options{ShortNames: []string{"p-"}}},
+		{name: "list kind", opts: crdgen.Options{ListKind: "proxy-list"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := tc.opts
+			opts.Native = true
+			opts.GVK = schema.GroupVersionKind{Group: "example.org", Version: "v1alpha1", Kind: "Proxy"}
+			opts.SpecJsonSchemaGetter = getter.Bytes(`{"type": "object"}`)
+
+			if res := crdgen.Generate(context.TODO(), opts); res.Err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package crdgen
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+// Offline configures the generation without network access: the
+// dependencies of the generated module are resolved from a pre-populated
+// module cache or from a vendor directory, never from a module proxy.
+type Offline struct {
+	// ModCacheDir is a module cache (GOMODCACHE) holding all the
+	// dependencies, e.g. populated by 'go mod download' on a connected
+	// host; when empty the default module cache is used.
+	ModCacheDir string
+	// VendorDir is a vendor directory holding all the dependencies,
+	// e.g. produced by 'go mod vendor'; when set ModCacheDir is ignored.
+	VendorDir string
+}
+
+// MissingModulesError reports the modules not available offline.
+type MissingModulesError struct {
+	// Modules lists the missing modules, e.g. "k8s.io/apimachinery@v0.33.0".
+	Modules []string
+}
+
+func (e *MissingModulesError) Error() string {
+	return fmt.Sprintf("modules not available offline: %s", strings.Join(e.Modules, ", "))
+}
+
+// Is makes MissingModulesError match ErrToolchain.
+func (e *MissingModulesError) Is(target error) bool {
+	return target == ErrToolchain
+}
+
+// env returns the environment of the go commands: no module proxy, no
+// checksum database, no toolchain download and the requested -mod mode.
+func (o *Offline) env() []string {
+	mode := "-mod=mod"
+	if len(o.VendorDir) > 0 {
+		mode = "-mod=vendor"
+	}
+
+	flags := []string{}
+	for _, el := range strings.Fields(os.Getenv("GOFLAGS")) {
+		if !strings.HasPrefix(el, "-mod=") && !strings.HasPrefix(el, "--mod=") {
+			flags = append(flags, el)
+		}
+	}
+	flags = append(flags, mode)
+
+	res := append(os.Environ(),
+		"GOPROXY=off",
+		"GOSUMDB=off",
+		"GOTOOLCHAIN=local",
+		"GOFLAGS="+strings.Join(flags, " "),
+	)
+	if len(o.ModCacheDir) > 0 && len(o.VendorDir) == 0 {
+		res = append(res, "GOMODCACHE="+o.ModCacheDir)
+	}
+
+	return res
+}
+
+// prepare checks that the requirements of the go.mod are available
+// offline; in vendor mode it also copies the vendor directory into the
+// workdir returning the go.mod requiring exactly the vendored modules.
+// The go binary and its environment resolve the default module cache.
+func (o *Offline) prepare(ctx context.Context, workdir string, gomod []byte, gobin string, env []string) ([]byte, error) {
+	f, err := modfile.Parse("go.mod", gomod, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(o.VendorDir) > 0 {
+		return o.prepareVendor(workdir, f)
+	}
+
+	dir := o.ModCacheDir
+	if len(dir) == 0 {
+		out, err := runCommand(ctx, workdir, env, gobin, "env", "GOMODCACHE")
+		if err != nil {
+			return nil, fmt.Errorf("%s: resolving the module cache: %w", strings.TrimSpace(string(out)), err)
+		}
+		dir = strings.TrimSpace(string(out))
+	}
+
+	missing := []string{}
+	for _, el := range f.Require {
+		if !inModCache(dir, el.Mod) {
+			missing = append(missing, el.Mod.String())
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &MissingModulesError{Modules: missing}
+	}
+
+	return gomod, nil
+}
+
+func (o *Offline) prepareVendor(workdir string, f *modfile.File) ([]byte, error) {
+	dat, err := os.ReadFile(filepath.Join(o.VendorDir, "modules.txt"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid vendor directory: %w", err)
+	}
+
+	vendored := parseVendorModules(dat)
+
+	missing := []string{}
+	for _, el := range f.Require {
+		if _, ok := vendored.versions[el.Mod.Path]; !ok {
+			missing = append(missing, el.Mod.String())
+		}
+	}
+	if len(missing) > 0 {
+		return nil, &MissingModulesError{Modules: missing}
+	}
+
+	// the go.mod must match the vendored modules exactly
+	for _, el := range append([]*modfile.Require{}, f.Require...) {
+		if err := f.DropRequire(el.Mod.Path); err != nil {
+			return nil, err
+		}
+	}
+	for _, path := range vendored.explicit {
+		if err := f.AddRequire(path, vendored.versions[path]); err != nil {
+			return nil, err
+		}
+	}
+	for _, el := range vendored.replaces {
+		if err := f.AddReplace(el.Old.Path, el.Old.Version, el.New.Path, el.New.Version); err != nil {
+			return nil, err
+		}
+	}
+	f.Cleanup()
+
+	if err := copyDir(o.VendorDir, filepath.Join(workdir, "vendor")); err != nil {
+		return nil, err
+	}
+
+	return f.Format()
+}
+
+// inModCache reports whether the module is available in the module cache.
+func inModCache(dir string, mod module.Version) bool {
+	path, err := module.EscapePath(mod.Path)
+	if err != nil {
+		return false
+	}
+	ver, err := module.EscapeVersion(mod.Version)
+	if err != nil {
+		return false
+	}
+
+	_, err = os.Stat(filepath.Join(dir, "cache", "download", path, "@v", ver+".mod"))
+	return err == nil
+}
+
+// vendorModules holds the modules listed in a vendor/modules.txt.
+type vendorModules struct {
+	versions map[string]string
+	explicit []string
+	replaces []modfile.Replace
+}
+
+// parseVendorModules parses a vendor/modules.txt, whose module lines
+// are like '# path version [=> path version]' optionally followed
+// by a '## explicit' annotation line.
+func parseVendorModules(dat []byte) vendorModules {
+	res := vendorModules{versions: map[string]string{}}
+
+	last := ""
+	sc := bufio.NewScanner(bytes.NewReader(dat))
+	for sc.Scan() {
+		line := sc.Text()
+
+		switch {
+		case strings.HasPrefix(line, "## "):
+			if last == "" {
+				continue
+			}
+			for _, el := range strings.Split(strings.TrimPrefix(line, "## "), ";") {
+				if strings.TrimSpace(el) == "explicit" {
+					res.explicit = append(res.explicit, last)
+				}
+			}
+
+		case strings.HasPrefix(line, "# "):
+			last = ""
+			parts := strings.Fields(strings.TrimPrefix(line, "# "))
+			if len(parts) < 2 || parts[1] == "=>" {
+				continue
+			}
+
+			last = parts[0]
+			res.versions[parts[0]] = parts[1]
+
+			if len(parts) == 5 && parts[2] == "=>" {
+				res.replaces = append(res.replaces, modfile.Replace{
+					Old: module.Version{Path: parts[0], Version: parts[1]},
+					New: module.Version{Path: parts[3], Version: parts[4]},
+				})
+			}
+		}
+	}
+
+	return res
+}
+
+var (
+	missingModuleRE  = regexp.MustCompile(`(\S+@v[^\s:]+): module lookup disabled by GOPROXY=off`)
+	missingPackageRE = regexp.MustCompile(`(?m)^\s*([^\s@:]+): module lookup disabled by GOPROXY=off`)
+)
+
+// missingModules extracts from the output of the go
+// commands the modules not available offline.
+func missingModules(out []byte) []string {
+	seen := map[string]bool{}
+	for _, el := range missingModuleRE.FindAllSubmatch(out, -1) {
+		seen[string(el[1])] = true
+	}
+	for _, el := range missingPackageRE.FindAllSubmatch(out, -1) {
+		seen[fmt.Sprintf("module providing package %s", el[1])] = true
+	}
+
+	res := make([]string, 0, len(seen))
+	for k := range seen {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+
+	return res
+}
+
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		if d.IsDir() {
+			err := os.MkdirAll(target, os.ModePerm)
+			if err != nil && !errors.Is(err, os.ErrExist) {
+				return err
+			}
+			return nil
+		}
+
+		dat, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, dat, 0666)
+	})
+}
@@ -0,0 +1,88 @@
+package crdgen
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseVendorModules(t *testing.T) {
+	dat := []byte(`# github.com/go-logr/logr v1.4.2
+## explicit; go 1.18
+github.com/go-logr/logr
+# golang.org/x/net v0.38.0
+golang.org/x/net/http2
+# k8s.io/apimachinery v0.33.0
+## explicit; go 1.24.0
+k8s.io/apimachinery/pkg/runtime
+# example.com/old v1.0.0 => example.com/new v1.1.0
+## explicit
+example.com/old
+# example.com/local => ./local
+`)
+
+	res := parseVendorModules(dat)
+
+	versions := map[string]string{
+		"github.com/go-logr/logr": "v1.4.2",
+		"golang.org/x/net":        "v0.38.0",
+		"k8s.io/apimachinery":     "v0.33.0",
+		"example.com/old":         "v1.0.0",
+	}
+	if !reflect.DeepEqual(res.versions, versions) {
+		t.Errorf("expected versions %v, got %v", versions, res.versions)
+	}
+
+	explicit := []string{"github.com/go-logr/logr", "k8s.io/apimachinery", "example.com/old"}
+	if !reflect.DeepEqual(res.explicit, explicit) {
+		t.Errorf("expected explicit %v, got %v", explicit, res.explicit)
+	}
+
+	if len(res.replaces) != 1 || res.replaces[0].New.Path != "example.com/new" {
+		t.Errorf("unexpected replaces: %v", res.replaces)
+	}
+}
+
+func TestMissingModules(t *testing.T) {
+	out := []byte(`go: downloading k8s.io/apimachinery v0.33.0
+go: x imports
+	github.com/foo/bar: k8s.io/apimachinery@v0.33.0: module lookup disabled by GOPROXY=off
+go: x imports
+	k8s.io/apimachinery/pkg/runtime: module lookup disabled by GOPROXY=off
+go: sigs.k8s.io/controller-tools@v0.18.0: module lookup disabled by GOPROXY=off
+`)
+
+	want := []string{
+		"k8s.io/apimachinery@v0.33.0",
+		"module providing package k8s.io/apimachinery/pkg/runtime",
+		"sigs.k8s.io/controller-tools@v0.18.0",
+	}
+
+	if got := missingModules(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if got := missingModules([]byte("exit status 1")); len(got) > 0 {
+		t.Errorf("expected no missing modules, got %v", got)
+	}
+}
+
+func TestOfflineEnv(t *testing.T) {
+	t.Setenv("GOFLAGS", "-mod=readonly -trimpath")
+
+	env := (&Offline{ModCacheDir: "/opt/modcache"}).env()
+	for _, el := range []string{"GOPROXY=off", "GOSUMDB=off", "GOTOOLCHAIN=local",
+		"GOFLAGS=-trimpath -mod=mod", "GOMODCACHE=/opt/modcache"} {
+		if !slices.Contains(env, el) {
+			t.Errorf("expected %s in the environment", el)
+		}
+	}
+
+	env = (&Offline{ModCacheDir: "/opt/modcache", VendorDir: "/opt/vendor"}).env()
+	if !slices.Contains(env, "GOFLAGS=-trimpath -mod=vendor") {
+		t.Errorf("expected vendor mode in the environment")
+	}
+	if slices.Contains(env, "GOMODCACHE=/opt/modcache") {
+		t.Errorf("expected the module cache to be ignored in vendor mode")
+	}
+}
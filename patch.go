@@ -0,0 +1,36 @@
+package crdgen
+
+import (
+	"github.com/krateoplatformops/crdgen/internal/crd"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+)
+
+// patchFunc changes a generated CRD.
+type patchFunc func(obj *apiextensionsv1.CustomResourceDefinition)
+
+// patch applies to the CRD manifest the settings controller-gen cannot
+// express with markers; the manifest is returned unchanged when there
+// is nothing to apply.
+func patch(manifest []byte, fns ...patchFunc) ([]byte, error) {
+	if len(fns) == 0 {
+		return manifest, nil
+	}
+
+	obj, err := crd.Unmarshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, fn := range fns {
+		fn(obj)
+	}
+
+	return crd.Marshal(obj)
+}
+
+// withListKind sets the kind of the resource list.
+func withListKind(listKind string) patchFunc {
+	return func(obj *apiextensionsv1.CustomResourceDefinition) {
+		obj.Spec.Names.ListKind = listKind
+	}
+}
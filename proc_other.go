@@ -0,0 +1,8 @@
+//go:build !unix
+
+package crdgen
+
+import "os/exec"
+
+// setProcessGroup is a no-op: only the command itself is killed on cancel.
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package crdgen
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup runs the command in its own process group so that
+// cancelling it also kills the children (e.g. the binary run by 'go run').
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
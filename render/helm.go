@@ -0,0 +1,80 @@
+package render
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+	"testing/fstest"
+
+	"github.com/krateoplatformops/crdgen/internal/crd"
+	"golang.org/x/mod/semver"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Chart is the metadata of the Helm chart holding the CRDs.
+type Chart struct {
+	// Name defaults to the group of the first CRD,
+	// dashed, followed by '-crds', e.g. 'example-org-crds'.
+	Name string `json:"name"`
+	// Version is the semantic version of the chart, by default '0.1.0'.
+	Version     string            `json:"version"`
+	AppVersion  string            `json:"appVersion,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Home        string            `json:"home,omitempty"`
+	Keywords    []string          `json:"keywords,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+var chartNameRE = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// HelmChart renders a Helm chart installing the CRDs from its
+// 'crds' directory, one file per CRD.
+func HelmChart(all []*apiextensionsv1.CustomResourceDefinition, opts Options) (fs.FS, error) {
+	objs, err := prepare(all, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := opts.Chart
+	if len(meta.Name) == 0 {
+		meta.Name = strings.ReplaceAll(strings.ToLower(objs[0].Spec.Group), ".", "-") + "-crds"
+	}
+	if !chartNameRE.MatchString(meta.Name) {
+		return nil, fmt.Errorf("invalid chart name '%s': must be lowercase letters, digits and dashes", meta.Name)
+	}
+
+	if len(meta.Version) == 0 {
+		meta.Version = "0.1.0"
+	}
+	// shorthands such as '1.2' are not semantic versions
+	ver, _, _ := strings.Cut("v"+meta.Version, "+")
+	if !semver.IsValid(ver) || semver.Canonical(ver) != ver {
+		return nil, fmt.Errorf("invalid chart version '%s': must be a semantic version", meta.Version)
+	}
+
+	dat, err := yaml.Marshal(struct {
+		APIVersion string `json:"apiVersion"`
+		Chart
+		Type string `json:"type"`
+	}{"v2", meta, "application"})
+	if err != nil {
+		return nil, err
+	}
+
+	res := fstest.MapFS{
+		"Chart.yaml": {Data: dat, Mode: 0644},
+	}
+
+	for _, obj := range objs {
+		dat, err := crd.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		res[path.Join("crds", fileName(obj))] = &fstest.MapFile{Data: dat, Mode: 0644}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,44 @@
+package render
+
+import (
+	"io/fs"
+	"testing/fstest"
+
+	"github.com/krateoplatformops/crdgen/internal/crd"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Kustomize renders a Kustomize base: a 'kustomization.yaml'
+// listing the CRDs as resources, one file per CRD.
+func Kustomize(all []*apiextensionsv1.CustomResourceDefinition, opts Options) (fs.FS, error) {
+	objs, err := prepare(all, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	res := fstest.MapFS{}
+	resources := make([]string, 0, len(objs))
+	for _, obj := range objs {
+		dat, err := crd.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		name := fileName(obj)
+		res[name] = &fstest.MapFile{Data: dat, Mode: 0644}
+		resources = append(resources, name)
+	}
+
+	dat, err := yaml.Marshal(map[string]any{
+		"apiVersion": "kustomize.config.k8s.io/v1beta1",
+		"kind":       "Kustomization",
+		"resources":  resources,
+	})
+	if err != nil {
+		return nil, err
+	}
+	res["kustomization.yaml"] = &fstest.MapFile{Data: dat, Mode: 0644}
+
+	return res, nil
+}
@@ -0,0 +1,122 @@
+// Package render lays out the generated CRDs for the delivery
+// paths: YAML or JSON manifests, a Helm chart and a Kustomize base.
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/krateoplatformops/crdgen/internal/crd"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Options customizes the rendered CRDs.
+type Options struct {
+	// Labels are added to the metadata of every CRD.
+	Labels map[string]string
+	// Chart describes the Helm chart, see HelmChart.
+	Chart Chart
+}
+
+// YAML renders the CRDs as a multi-document YAML, in order.
+func YAML(all []*apiextensionsv1.CustomResourceDefinition, opts Options) ([]byte, error) {
+	objs, err := prepare(all, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := bytes.Buffer{}
+	for _, obj := range objs {
+		dat, err := crd.Marshal(obj)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(dat)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// JSON renders a single CRD as a JSON object and several
+// CRDs as a JSON 'v1/List', ready to be sent to the API server.
+func JSON(all []*apiextensionsv1.CustomResourceDefinition, opts Options) ([]byte, error) {
+	objs, err := prepare(all, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]any, 0, len(objs))
+	for _, obj := range objs {
+		dict, err := crd.Object(obj)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, dict)
+	}
+
+	var doc any = items[0]
+	if len(items) > 1 {
+		doc = map[string]any{
+			"apiVersion": "v1",
+			"kind":       "List",
+			"items":      items,
+		}
+	}
+
+	dat, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(dat, '\n'), nil
+}
+
+// prepare validates the options returning labeled copies of the CRDs.
+func prepare(all []*apiextensionsv1.CustomResourceDefinition, opts Options) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	if len(all) == 0 {
+		return nil, fmt.Errorf("no CRD to render")
+	}
+
+	keys := make([]string, 0, len(opts.Labels))
+	for k := range opts.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if errs := validation.IsQualifiedName(k); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid label key '%s': %v", k, errs)
+		}
+		if errs := validation.IsValidLabelValue(opts.Labels[k]); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid value of label '%s': %v", k, errs)
+		}
+	}
+
+	res := make([]*apiextensionsv1.CustomResourceDefinition, 0, len(all))
+	seen := map[string]bool{}
+	for _, el := range all {
+		if seen[el.Name] {
+			return nil, fmt.Errorf("duplicate CRD '%s'", el.Name)
+		}
+		seen[el.Name] = true
+
+		obj := el.DeepCopy()
+		if len(opts.Labels) > 0 && obj.Labels == nil {
+			obj.Labels = make(map[string]string, len(opts.Labels))
+		}
+		for k, v := range opts.Labels {
+			obj.Labels[k] = v
+		}
+		res = append(res, obj)
+	}
+
+	return res, nil
+}
+
+// fileName returns the file name used by controller-gen
+// for the CRD, e.g. 'example.org_xapps.yaml'.
+func fileName(obj *apiextensionsv1.CustomResourceDefinition) string {
+	return fmt.Sprintf("%s_%s.yaml", obj.Spec.Group, obj.Spec.Names.Plural)
+}
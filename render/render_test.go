@@ -0,0 +1,191 @@
+package render_test
+
+import (
+	"encoding/json"
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen/render"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+func TestJSON(t *testing.T) {
+	opts := render.Options{
+		Labels: map[string]string{"app.kubernetes.io/part-of": "krateo"},
+	}
+
+	dat, err := render.JSON(testCRDs("Xapp"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := apiextensionsv1.CustomResourceDefinition{}
+	if err := json.Unmarshal(dat, &obj); err != nil {
+		t.Fatal(err)
+	}
+	if obj.Name != "xapps.example.org" || obj.Labels["app.kubernetes.io/part-of"] != "krateo" {
+		t.Errorf("unexpected CRD: %s", dat)
+	}
+	if strings.Contains(string(dat), `"status"`) {
+		t.Errorf("expected no status:\n%s", dat)
+	}
+
+	dat, err = render.JSON(testCRDs("Xapp", "Bucket"), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := struct {
+		Kind  string
+		Items []apiextensionsv1.CustomResourceDefinition
+	}{}
+	if err := json.Unmarshal(dat, &list); err != nil {
+		t.Fatal(err)
+	}
+	if list.Kind != "List" || len(list.Items) != 2 || list.Items[1].Name != "buckets.example.org" {
+		t.Errorf("unexpected list: %s", dat)
+	}
+}
+
+func TestYAML(t *testing.T) {
+	all := testCRDs("Xapp", "Bucket")
+
+	dat, err := render.YAML(all, render.Options{Labels: map[string]string{"team": "platform"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Count(string(dat), "team: platform") != 2 {
+		t.Errorf("expected the label on every CRD:\n%s", dat)
+	}
+	if strings.Index(string(dat), "xapps.example.org") > strings.Index(string(dat), "buckets.example.org") {
+		t.Errorf("expected the CRDs in order:\n%s", dat)
+	}
+	if len(all[0].Labels) > 0 {
+		t.Errorf("expected the CRDs unchanged")
+	}
+}
+
+func TestHelmChart(t *testing.T) {
+	fsys, err := render.HelmChart(testCRDs("Xapp", "Bucket"), render.Options{
+		Chart: render.Chart{
+			Version:    "1.2.0",
+			AppVersion: "v0.5.0",
+			Keywords:   []string{"krateo"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dat, err := fs.ReadFile(fsys, "Chart.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta := map[string]any{}
+	if err := yaml.Unmarshal(dat, &meta); err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]any{
+		"apiVersion": "v2",
+		"name":       "example-org-crds",
+		"version":    "1.2.0",
+		"appVersion": "v0.5.0",
+	} {
+		if meta[k] != want {
+			t.Errorf("expected %s '%v', got '%v'", k, want, meta[k])
+		}
+	}
+
+	for _, name := range []string{"crds/example.org_xapps.yaml", "crds/example.org_buckets.yaml"} {
+		if _, err := fs.Stat(fsys, name); err != nil {
+			t.Error(err)
+		}
+	}
+
+	for _, chart := range []render.Chart{{Name: "Example_CRDs"}, {Version: "1.2"}} {
+		if _, err := render.HelmChart(testCRDs("Xapp"), render.Options{Chart: chart}); err == nil {
+			t.Errorf("expected an error for chart %+v", chart)
+		}
+	}
+}
+
+func TestKustomize(t *testing.T) {
+	fsys, err := render.Kustomize(testCRDs("Xapp", "Bucket"), render.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dat, err := fs.ReadFile(fsys, "kustomization.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+resources:
+- example.org_xapps.yaml
+- example.org_buckets.yaml
+`
+	if string(dat) != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, dat)
+	}
+
+	if _, err := fs.Stat(fsys, "example.org_buckets.yaml"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+	if _, err := render.YAML(nil, render.Options{}); err == nil {
+		t.Errorf("expected an error without CRDs")
+	}
+
+	if _, err := render.YAML(testCRDs("Xapp", "Xapp"), render.Options{}); err == nil {
+		t.Errorf("expected an error for duplicate CRDs")
+	}
+
+	opts := render.Options{Labels: map[string]string{"team": "not valid"}}
+	if _, err := render.YAML(testCRDs("Xapp"), opts); err == nil {
+		t.Errorf("expected an error for an invalid label")
+	}
+}
+
+func testCRDs(kinds ...string) []*apiextensionsv1.CustomResourceDefinition {
+	res := []*apiextensionsv1.CustomResourceDefinition{}
+	for _, kind := range kinds {
+		plural := strings.ToLower(kind) + "s"
+		res = append(res, &apiextensionsv1.CustomResourceDefinition{
+			TypeMeta: metav1.TypeMeta{
+				APIVersion: "apiextensions.k8s.io/v1",
+				Kind:       "CustomResourceDefinition",
+			},
+			ObjectMeta: metav1.ObjectMeta{Name: plural + ".example.org"},
+			Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+				Group: "example.org",
+				Names: apiextensionsv1.CustomResourceDefinitionNames{
+					Kind:     kind,
+					ListKind: kind + "List",
+					Plural:   plural,
+					Singular: strings.ToLower(kind),
+				},
+				Scope: apiextensionsv1.NamespaceScoped,
+				Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+					{
+						Name:    "v1alpha1",
+						Served:  true,
+						Storage: true,
+						Schema: &apiextensionsv1.CustomResourceValidation{
+							OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{Type: "object"},
+						},
+					},
+				},
+			},
+		})
+	}
+	return res
+}
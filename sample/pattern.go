@@ -0,0 +1,127 @@
+package sample
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// matching returns a short string matching the regular expression,
+// of at least minLen and at most maxLen runes (a negative maxLen is
+// no limit): the repetitions of the expression are unrolled until
+// minLen is reached.
+func matching(pattern string, minLen, maxLen int) (string, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return "", err
+	}
+	re = re.Simplify()
+
+	res := ""
+	for extra := 0; extra <= minLen; extra++ {
+		g := generator{extra: extra}
+		g.generate(re)
+
+		res = g.sb.String()
+		if utf8.RuneCountInString(res) >= minLen {
+			break
+		}
+	}
+
+	n := utf8.RuneCountInString(res)
+	if ok, _ := regexp.MatchString(pattern, res); !ok || n < minLen || maxLen >= 0 && n > maxLen {
+		return "", fmt.Errorf("cannot generate a value matching '%s' of length %s", pattern, lengths(minLen, maxLen))
+	}
+	return res, nil
+}
+
+// lengths describes the length bounds of a value.
+func lengths(minLen, maxLen int) string {
+	if maxLen < 0 {
+		return fmt.Sprintf("%d or more", minLen)
+	}
+	return fmt.Sprintf("%d to %d", minLen, maxLen)
+}
+
+// generator writes the shortest expansion of an expression, but
+// for the extra repetitions spent on the first repeats met.
+type generator struct {
+	sb    strings.Builder
+	extra int
+}
+
+// repeat writes the expression min times, plus the extra
+// repetitions allowed by max (-1 is no limit).
+func (g *generator) repeat(re *syntax.Regexp, min, max int) {
+	n := g.extra
+	if max >= 0 && n > max-min {
+		n = max - min
+	}
+	g.extra -= n
+
+	for i := 0; i < min+n; i++ {
+		g.generate(re)
+	}
+}
+
+// generate writes the expansion of the expression,
+// preferring lowercase letters and digits in the character classes.
+func (g *generator) generate(re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 {
+				r = unicode.ToLower(r)
+			}
+			g.sb.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		g.sb.WriteRune(classRune(re.Rune))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		g.sb.WriteRune('a')
+	case syntax.OpCapture:
+		g.generate(re.Sub[0])
+	case syntax.OpConcat:
+		for _, el := range re.Sub {
+			g.generate(el)
+		}
+	case syntax.OpAlternate:
+		g.generate(re.Sub[0])
+	case syntax.OpStar:
+		g.repeat(re.Sub[0], 0, -1)
+	case syntax.OpPlus:
+		g.repeat(re.Sub[0], 1, -1)
+	case syntax.OpQuest:
+		g.repeat(re.Sub[0], 0, 1)
+	case syntax.OpRepeat:
+		g.repeat(re.Sub[0], re.Min, re.Max)
+	}
+	// empty matches, anchors and word boundaries add nothing
+}
+
+// classRune picks a rune of the class: pairs of ranges [lo, hi].
+func classRune(ranges []rune) rune {
+	for _, want := range []rune{'a', '0', 'A'} {
+		for i := 0; i+1 < len(ranges); i += 2 {
+			if ranges[i] <= want && want <= ranges[i+1] {
+				return want
+			}
+		}
+	}
+
+	for i := 0; i+1 < len(ranges); i += 2 {
+		for r := ranges[i]; r <= ranges[i+1]; r++ {
+			if unicode.IsPrint(r) && !unicode.IsSpace(r) {
+				return r
+			}
+		}
+	}
+
+	if len(ranges) > 0 {
+		return ranges[0]
+	}
+	return 'a'
+}
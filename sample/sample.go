@@ -0,0 +1,332 @@
+// Package sample builds sample custom resources from the schema of a
+// CRD, showing what a valid object looks like.
+package sample
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// Options customizes the sample.
+type Options struct {
+	// Version of the sample, by default the storage version.
+	Version string
+	// All includes every field, not only the required ones.
+	All bool
+	// Name of the sample, by default '<singular>-sample'.
+	Name string
+}
+
+// YAML returns the sample custom resource as a YAML document.
+func YAML(crd *apiextensionsv1.CustomResourceDefinition, opts Options) ([]byte, error) {
+	obj, err := Object(crd, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dat, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte("---\n"), dat...), nil
+}
+
+// JSON returns the sample custom resource as a JSON object.
+func JSON(crd *apiextensionsv1.CustomResourceDefinition, opts Options) ([]byte, error) {
+	obj, err := Object(crd, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dat, err := json.MarshalIndent(obj, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(dat, '\n'), nil
+}
+
+// Object returns the sample custom resource: its spec holds the
+// defaults, the first enum values, placeholders matching the patterns
+// and formats and the minimal values satisfying the bounds.
+func Object(crd *apiextensionsv1.CustomResourceDefinition, opts Options) (map[string]any, error) {
+	ver, err := version(crd, opts.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	name := opts.Name
+	if len(name) == 0 {
+		name = crd.Spec.Names.Singular + "-sample"
+	}
+
+	res := map[string]any{
+		"apiVersion": crd.Spec.Group + "/" + ver.Name,
+		"kind":       crd.Spec.Names.Kind,
+		"metadata": map[string]any{
+			"name": name,
+		},
+	}
+
+	if ver.Schema == nil || ver.Schema.OpenAPIV3Schema == nil {
+		return res, nil
+	}
+
+	// the spec is always shown, the status is owned by the controllers
+	if spec, ok := ver.Schema.OpenAPIV3Schema.Properties["spec"]; ok {
+		b := &builder{all: opts.All}
+		val, err := b.value("spec", &spec)
+		if err != nil {
+			return nil, err
+		}
+		res["spec"] = val
+	}
+
+	return res, nil
+}
+
+// version returns the named version, or the storage one.
+func version(crd *apiextensionsv1.CustomResourceDefinition, name string) (*apiextensionsv1.CustomResourceDefinitionVersion, error) {
+	for i := range crd.Spec.Versions {
+		el := &crd.Spec.Versions[i]
+		if (len(name) == 0 && el.Storage) || el.Name == name {
+			return el, nil
+		}
+	}
+
+	if len(name) == 0 {
+		return nil, fmt.Errorf("CRD '%s' has no storage version", crd.Name)
+	}
+	return nil, fmt.Errorf("CRD '%s' has no version '%s'", crd.Name, name)
+}
+
+// builder walks a schema building its sample value.
+type builder struct {
+	all bool
+}
+
+func (b *builder) value(path string, schema *apiextensionsv1.JSONSchemaProps) (any, error) {
+	if schema.Default != nil {
+		return decode(path, schema.Default)
+	}
+
+	if len(schema.Enum) > 0 {
+		return decode(path, &schema.Enum[0])
+	}
+
+	switch {
+	case schema.XIntOrString:
+		return placeholder(path, schema)
+	case schema.Type == "object" || len(schema.Properties) > 0:
+		return b.object(path, schema)
+	case schema.Type == "array":
+		return b.array(path, schema)
+	case schema.Type == "string":
+		return placeholder(path, schema)
+	case schema.Type == "integer":
+		return integer(schema), nil
+	case schema.Type == "number":
+		return number(schema), nil
+	case schema.Type == "boolean":
+		return false, nil
+	default:
+		return map[string]any{}, nil
+	}
+}
+
+func (b *builder) object(path string, schema *apiextensionsv1.JSONSchemaProps) (any, error) {
+	res := map[string]any{}
+
+	names := make([]string, 0, len(schema.Properties))
+	for k := range schema.Properties {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	required := map[string]bool{}
+	for _, k := range schema.Required {
+		required[k] = true
+	}
+
+	for _, k := range names {
+		if !b.all && !required[k] {
+			continue
+		}
+
+		sub := schema.Properties[k]
+		val, err := b.value(path+"."+k, &sub)
+		if err != nil {
+			return nil, err
+		}
+		res[k] = val
+	}
+
+	// maps show a single entry
+	ap := schema.AdditionalProperties
+	if len(schema.Properties) == 0 && ap != nil && ap.Schema != nil &&
+		(schema.MaxProperties == nil || *schema.MaxProperties > 0) {
+		val, err := b.value(path+".*", ap.Schema)
+		if err != nil {
+			return nil, err
+		}
+		res["key"] = val
+	}
+
+	return res, nil
+}
+
+func (b *builder) array(path string, schema *apiextensionsv1.JSONSchemaProps) (any, error) {
+	// arrays show at least an item
+	n := int64(1)
+	if schema.MinItems != nil && *schema.MinItems > n {
+		n = *schema.MinItems
+	}
+	if schema.MaxItems != nil && n > *schema.MaxItems {
+		n = *schema.MaxItems
+	}
+
+	res := make([]any, 0, n)
+	if schema.Items == nil || schema.Items.Schema == nil {
+		return res, nil
+	}
+
+	for i := int64(0); i < n; i++ {
+		val, err := b.value(path+"[*]", schema.Items.Schema)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, val)
+	}
+
+	return res, nil
+}
+
+func decode(path string, val *apiextensionsv1.JSON) (any, error) {
+	var res any
+	if err := json.Unmarshal(val.Raw, &res); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return res, nil
+}
+
+// integer returns the integer closest to zero satisfying the bounds.
+func integer(schema *apiextensionsv1.JSONSchemaProps) int64 {
+	lo, hi := math.Inf(-1), math.Inf(1)
+	if schema.Minimum != nil {
+		lo = math.Ceil(*schema.Minimum)
+		if schema.ExclusiveMinimum && lo == *schema.Minimum {
+			lo++
+		}
+	}
+	if schema.Maximum != nil {
+		hi = math.Floor(*schema.Maximum)
+		if schema.ExclusiveMaximum && hi == *schema.Maximum {
+			hi--
+		}
+	}
+
+	res := math.Max(lo, math.Min(0, hi))
+	if schema.MultipleOf != nil && *schema.MultipleOf >= 1 {
+		step := math.Round(*schema.MultipleOf)
+		if up := math.Ceil(res/step) * step; up <= hi {
+			res = up
+		} else {
+			res = math.Floor(res/step) * step
+		}
+	}
+	return int64(res)
+}
+
+// number returns the value closest to zero satisfying the bounds
+// and, when one fits in them, a multiple of multipleOf.
+func number(schema *apiextensionsv1.JSONSchemaProps) float64 {
+	// the margin from the exclusive bounds
+	margin := 1.0
+	if schema.Minimum != nil && schema.Maximum != nil {
+		margin = (*schema.Maximum - *schema.Minimum) / 2
+	}
+
+	res := 0.0
+	if lo := schema.Minimum; lo != nil && (res < *lo || res == *lo && schema.ExclusiveMinimum) {
+		res = *lo
+		if schema.ExclusiveMinimum {
+			res += margin
+		}
+	}
+	if hi := schema.Maximum; hi != nil && (res > *hi || res == *hi && schema.ExclusiveMaximum) {
+		res = *hi
+		if schema.ExclusiveMaximum {
+			res -= margin
+		}
+	}
+	if schema.MultipleOf != nil && *schema.MultipleOf > 0 {
+		step := *schema.MultipleOf
+		up := math.Ceil(res/step) * step
+		if hi := schema.Maximum; hi == nil || up < *hi || up == *hi && !schema.ExclusiveMaximum {
+			res = up
+		} else {
+			res = math.Floor(res/step) * step
+		}
+	}
+	return res
+}
+
+// placeholder returns a string satisfying format,
+// pattern and length of the schema, if possible.
+func placeholder(path string, schema *apiextensionsv1.JSONSchemaProps) (string, error) {
+	res, ok := formats[schema.Format]
+	switch {
+	case ok:
+	case len(schema.Pattern) > 0:
+		minLen, maxLen := 0, -1
+		if schema.MinLength != nil {
+			minLen = int(*schema.MinLength)
+		}
+		if schema.MaxLength != nil {
+			maxLen = int(*schema.MaxLength)
+		}
+
+		res, err := matching(schema.Pattern, minLen, maxLen)
+		if err != nil {
+			return "", fmt.Errorf("%s: %w", path, err)
+		}
+		return res, nil
+	default:
+		res = strings.ReplaceAll(strings.TrimPrefix(path, "spec."), ".", "-")
+		res = strings.NewReplacer("[*]", "", "*", "key").Replace(res)
+	}
+
+	if schema.MinLength != nil {
+		for int64(len(res)) < *schema.MinLength {
+			res += "x"
+		}
+	}
+	if schema.MaxLength != nil && int64(len(res)) > *schema.MaxLength {
+		res = res[:*schema.MaxLength]
+	}
+
+	return res, nil
+}
+
+// formats holds a valid placeholder of the formats known by the API server.
+var formats = map[string]string{
+	"date":      "2024-01-01",
+	"date-time": "2024-01-01T00:00:00Z",
+	"datetime":  "2024-01-01T00:00:00Z",
+	"duration":  "1h",
+	"email":     "user@example.org",
+	"hostname":  "example.org",
+	"ipv4":      "192.0.2.1",
+	"ipv6":      "2001:db8::1",
+	"cidr":      "192.0.2.0/24",
+	"mac":       "00:00:5e:00:53:01",
+	"uri":       "https://example.org",
+	"uuid":      "123e4567-e89b-12d3-a456-426614174000",
+	"byte":      "c2FtcGxl",
+	"password":  "password",
+}
@@ -0,0 +1,212 @@
+package sample_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"github.com/krateoplatformops/crdgen/internal/ptr"
+	"github.com/krateoplatformops/crdgen/sample"
+	"github.com/krateoplatformops/crdgen/validator"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+const spec = `{
+	"type": "object",
+	"required": ["name", "replicas", "mode", "zone", "ports", "contact"],
+	"properties": {
+		"name": {"type": "string", "pattern": "^[a-z][a-z0-9-]{2,}$"},
+		"replicas": {"type": "integer", "minimum": 3, "maximum": 9},
+		"ratio": {"type": "number", "minimum": 0, "exclusiveMinimum": true, "maximum": 1},
+		"mode": {"type": "string", "enum": ["safe", "fast"]},
+		"zone": {"type": "string", "default": "eu-west-1"},
+		"contact": {"type": "string", "format": "email"},
+		"ports": {
+			"type": "array",
+			"minItems": 1,
+			"items": {
+				"type": "object",
+				"required": ["port"],
+				"properties": {
+					"port": {"type": "integer", "minimum": 1024, "multipleOf": 10},
+					"protocol": {"type": "string", "enum": ["TCP", "UDP"]}
+				}
+			}
+		},
+		"labels": {"type": "object", "additionalProperties": {"type": "string"}},
+		"enabled": {"type": "boolean"}
+	}
+}`
+
+func TestObject(t *testing.T) {
+	res := crdgen.Generate(context.TODO(), crdgen.Options{
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Xapp",
+		},
+		Native:               true,
+		Managed:              true,
+		SpecJsonSchemaGetter: getter.Bytes(spec),
+	})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	crd := res.CRDs["xapps.example.org"]
+
+	tests := []struct {
+		name string
+		opts sample.Options
+		want string
+	}{
+		{
+			name: "required",
+			want: `
+apiVersion: example.org/v1alpha1
+kind: Xapp
+metadata:
+  name: xapp-sample
+spec:
+  contact: contact
+  mode: safe
+  name: aaa
+  ports:
+  - port: 1030
+  replicas: 3
+  zone: eu-west-1
+`,
+		},
+		{
+			name: "all",
+			opts: sample.Options{All: true, Name: "demo"},
+			want: `
+apiVersion: example.org/v1alpha1
+kind: Xapp
+metadata:
+  name: demo
+spec:
+  contact: contact
+  enabled: false
+  labels:
+    key: labels-key
+  mode: safe
+  name: aaa
+  ports:
+  - port: 1030
+    protocol: TCP
+  ratio: 0
+  replicas: 3
+  zone: eu-west-1
+`,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			dat, err := sample.YAML(crd, tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got, want any
+			if err := yaml.Unmarshal(dat, &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := yaml.Unmarshal([]byte(tc.want), &want); err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("expected:%s\ngot:\n%s", tc.want, dat)
+			}
+
+			validate(t, crd, got.(map[string]any))
+		})
+	}
+
+	if _, err := sample.Object(crd, sample.Options{Version: "v1"}); err == nil {
+		t.Errorf("expected an error for a missing version")
+	}
+}
+
+func TestBounds(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "xapps.example.org"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.org",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "Xapp", Singular: "xapp"},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:    "v1",
+				Served:  true,
+				Storage: true,
+				Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"spec": {
+								Type: "object",
+								Properties: map[string]apiextensionsv1.JSONSchemaProps{
+									"created": {Type: "string", Format: "date-time"},
+									"ratio":   {Type: "number", Minimum: ptr.To(0.0), ExclusiveMinimum: true, Maximum: ptr.To(1.0)},
+									"offset":  {Type: "integer", Maximum: ptr.To(-5.0), ExclusiveMaximum: true},
+									"weight":  {Type: "number", Minimum: ptr.To(0.1), MultipleOf: ptr.To(0.25)},
+									"code":    {Type: "string", MinLength: ptr.To[int64](6), MaxLength: ptr.To[int64](8)},
+									"version": {Type: "string", Pattern: `^v[0-9]+(alpha|beta)?[0-9]*$`},
+									"digits":  {Type: "string", Pattern: `^[0-9]+$`, MinLength: ptr.To[int64](3)},
+									"tag":     {Type: "string", Pattern: `^[a-z]+-[0-9]{1,2}$`, MinLength: ptr.To[int64](6), MaxLength: ptr.To[int64](6)},
+								},
+							},
+						},
+					},
+				},
+			}},
+		},
+	}
+
+	got, err := sample.Object(crd, sample.Options{All: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]any{
+		"created": "2024-01-01T00:00:00Z",
+		"ratio":   0.5,
+		"offset":  int64(-6),
+		"weight":  0.25,
+		"code":    "codexx",
+		"version": "v0",
+		"digits":  "000",
+		"tag":     "aaaa-0",
+	}
+	if !reflect.DeepEqual(got["spec"], want) {
+		t.Errorf("expected %v, got %v", want, got["spec"])
+	}
+
+	validate(t, crd, got)
+
+	crd.Spec.Versions[0].Schema.OpenAPIV3Schema.Properties["spec"].Properties["tag"] = apiextensionsv1.JSONSchemaProps{
+		Type: "string", Pattern: `^[a-z]{2}$`, MinLength: ptr.To[int64](3),
+	}
+	if _, err := sample.Object(crd, sample.Options{All: true}); err == nil {
+		t.Errorf("expected an error for a pattern not matching the length")
+	}
+}
+
+// validate checks that the API server would accept the sample.
+func validate(t *testing.T, crd *apiextensionsv1.CustomResourceDefinition, obj map[string]any) {
+	t.Helper()
+
+	v, err := validator.New(crd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res := v.Object(context.TODO(), obj); !res.Valid() {
+		t.Errorf("expected a valid sample, got violations: %v", res.Violations)
+	}
+}
@@ -0,0 +1,145 @@
+package crdgen
+
+import (
+	"archive/tar"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+
+	"github.com/krateoplatformops/crdgen/internal/coder"
+	"golang.org/x/mod/module"
+)
+
+// Scaffold exports the generated Go module, e.g. to
+// bootstrap the API packages of a real project.
+type Scaffold struct {
+	// Dir, when set, receives a copy of the module;
+	// it must not exist or be empty.
+	Dir string
+	// Module is the module path, by default
+	// 'github.com/krateoplatformops/<WorkDir>'.
+	Module string
+	// Boilerplate heads the deepcopy files, by default the one
+	// of the Options; it must be made of Go comments.
+	Boilerplate string
+	// Layout is the directory of the API packages relative to the
+	// module root where '{group}', '{shortGroup}' and '{version}'
+	// are replaced, by default 'apis/{group}/{version}'.
+	Layout string
+}
+
+// validate checks the scaffold options before any generation.
+func (s *Scaffold) validate() error {
+	if len(s.Module) > 0 {
+		if err := module.CheckImportPath(s.Module); err != nil {
+			return fmt.Errorf("invalid scaffold module: %w", err)
+		}
+	}
+
+	if len(s.Layout) > 0 {
+		if err := coder.ValidateLayout(s.Layout); err != nil {
+			return err
+		}
+	}
+
+	if err := validateBoilerplate(s.Boilerplate); err != nil {
+		return fmt.Errorf("invalid scaffold boilerplate: %w", err)
+	}
+
+	if len(s.Dir) == 0 {
+		return nil
+	}
+
+	files, err := os.ReadDir(s.Dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	if len(files) > 0 {
+		return fmt.Errorf("scaffold directory '%s' is not empty", s.Dir)
+	}
+
+	return nil
+}
+
+// readModule reads the whole generated module in memory.
+func readModule(workdir string) (fstest.MapFS, error) {
+	res := fstest.MapFS{}
+
+	err := filepath.WalkDir(workdir, func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(workdir, name)
+		if err != nil {
+			return err
+		}
+
+		nfo, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		dat, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		res[filepath.ToSlash(rel)] = &fstest.MapFile{
+			Data:    dat,
+			Mode:    nfo.Mode().Perm(),
+			ModTime: nfo.ModTime(),
+		}
+		return nil
+	})
+
+	return res, err
+}
+
+// export replaces the manifests of the module with the final
+// ones and, when Dir is set, copies the module there.
+func (s *Scaffold) export(fsys fstest.MapFS, all []*crdPlan, manifests map[string][]byte) (fs.FS, error) {
+	for _, p := range all {
+		group := p.kind.GVK.Group
+		name := path.Join("crds", fmt.Sprintf("%s_%s.yaml", group, strings.TrimSuffix(p.name, "."+group)))
+
+		el, ok := fsys[name]
+		if !ok {
+			el = &fstest.MapFile{Mode: 0644}
+			fsys[name] = el
+		}
+		el.Data = manifests[p.name]
+	}
+
+	if len(s.Dir) == 0 {
+		return fsys, nil
+	}
+
+	if err := os.CopyFS(s.Dir, fsys); err != nil {
+		return nil, fmt.Errorf("exporting scaffold: %w", err)
+	}
+
+	return os.DirFS(s.Dir), nil
+}
+
+// WriteScaffold writes the scaffold as a tar stream.
+func (r Result) WriteScaffold(w io.Writer) error {
+	if r.Scaffold == nil {
+		return errors.New("no scaffold generated")
+	}
+
+	tw := tar.NewWriter(w)
+	if err := tw.AddFS(r.Scaffold); err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,51 @@
+package crdgen_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestScaffoldValidation(t *testing.T) {
+	full := t.TempDir()
+	if err := os.WriteFile(filepath.Join(full, "go.mod"), []byte("module example.com/x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		native   bool
+		scaffold crdgen.Scaffold
+	}{
+		{name: "native", native: true},
+		{name: "non empty dir", scaffold: crdgen.Scaffold{Dir: full}},
+		{name: "invalid module", scaffold: crdgen.Scaffold{Module: "example.com/a b"}},
+		{name: "layout without version", scaffold: crdgen.Scaffold{Layout: "api/{group}"}},
+		{name: "absolute layout", scaffold: crdgen.Scaffold{Layout: "/api/{version}"}},
+		{name: "layout escaping the module", scaffold: crdgen.Scaffold{Layout: "../api/{version}"}},
+		{name: "boilerplate", scaffold: crdgen.Scaffold{Boilerplate: "Copyright 2025 ACME"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res := crdgen.Generate(context.TODO(), crdgen.Options{
+				GVK: schema.GroupVersionKind{
+					Group:   "example.org",
+					Version: "v1alpha1",
+					Kind:    "Xapp",
+				},
+				Native:               tc.native,
+				SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+				Scaffold:             &tc.scaffold,
+			})
+			if res.Err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
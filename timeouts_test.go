@@ -0,0 +1,56 @@
+package crdgen_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestFetchTimeout(t *testing.T) {
+	res := crdgen.Generate(context.Background(), crdgen.Options{
+		GVK: schema.GroupVersionKind{
+			Group:   "demo.example.org",
+			Version: "v1alpha1",
+			Kind:    "Demo",
+		},
+		Native:               true,
+		SpecJsonSchemaGetter: blockingSchema{},
+		Timeouts: crdgen.Timeouts{
+			Fetch: 50 * time.Millisecond,
+		},
+	})
+	if !errors.Is(res.Err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline exceeded error, got: %v", res.Err)
+	}
+}
+
+func TestCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	res := crdgen.Generate(ctx, crdgen.Options{
+		GVK: schema.GroupVersionKind{
+			Group:   "demo.example.org",
+			Version: "v1alpha1",
+			Kind:    "Demo",
+		},
+		Native:               true,
+		SpecJsonSchemaGetter: getter.Bytes(`{"type": "object"}`),
+	})
+	if !errors.Is(res.Err, context.Canceled) {
+		t.Fatalf("expected a context canceled error, got: %v", res.Err)
+	}
+}
+
+// blockingSchema never returns a schema before the context is done.
+type blockingSchema struct{}
+
+func (blockingSchema) Get(ctx context.Context) ([]byte, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
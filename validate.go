@@ -0,0 +1,96 @@
+package crdgen
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/validation"
+)
+
+// Violation is a rule of the API server broken by a generated CRD.
+type Violation struct {
+	// CRD is the name of the CRD, e.g. 'xapps.example.org'.
+	CRD string
+	// Field is the JSON path of the offending field, e.g.
+	// 'spec.versions[0].schema.openAPIV3Schema.properties[spec].default'.
+	Field string
+	// Type is the kind of violation, e.g. 'Invalid value' or 'Required value'.
+	Type   string
+	Detail string
+}
+
+func (v Violation) String() string {
+	return fmt.Sprintf("%s: %s: %s: %s", v.CRD, v.Field, v.Type, v.Detail)
+}
+
+// Validate checks the CRD with the rules applied by the apiextensions API
+// server on creation: the names, the versions, the structural schemas,
+// the defaults (which must be pruned and valid) and the CEL rules.
+func Validate(ctx context.Context, obj *apiextensionsv1.CustomResourceDefinition) ([]Violation, error) {
+	in := obj.DeepCopy()
+	// the API server defaults the object before validating it
+	apiextensionsv1.SetObjectDefaults_CustomResourceDefinition(in)
+
+	out := &apiextensions.CustomResourceDefinition{}
+	err := apiextensionsv1.Convert_v1_CustomResourceDefinition_To_apiextensions_CustomResourceDefinition(in, out, nil)
+	if err != nil {
+		return nil, fmt.Errorf("converting CRD '%s': %w", obj.Name, err)
+	}
+
+	// the conversion hoists the settings shared by all the
+	// versions, the paths of the violations are reported as in v1
+	ver := "spec.versions[*]"
+	if len(out.Spec.Versions) == 1 {
+		ver = "spec.versions[0]"
+	}
+	hoisted := map[string]string{
+		"spec.validation":               ver + ".schema",
+		"spec.subresources":             ver + ".subresources",
+		"spec.additionalPrinterColumns": ver + ".additionalPrinterColumns",
+	}
+
+	res := []Violation{}
+	for _, el := range validation.ValidateCustomResourceDefinition(ctx, out) {
+		res = append(res, Violation{
+			CRD:    obj.Name,
+			Field:  v1Path(hoisted, el.Field),
+			Type:   el.Type.String(),
+			Detail: el.Detail,
+		})
+	}
+
+	return res, nil
+}
+
+// v1Path rewrites the path of a field hoisted by the conversion.
+func v1Path(hoisted map[string]string, field string) string {
+	for prefix, path := range hoisted {
+		rest, ok := strings.CutPrefix(field, prefix)
+		if ok && (len(rest) == 0 || rest[0] == '.' || rest[0] == '[') {
+			return path + rest
+		}
+	}
+	return field
+}
+
+// validateAll validates the CRDs in the order of the plans.
+func validateAll(ctx context.Context, all []*crdPlan, crds map[string]*apiextensionsv1.CustomResourceDefinition) ([]Violation, error) {
+	res := []Violation{}
+	for _, p := range all {
+		obj, ok := crds[p.name]
+		if !ok {
+			continue
+		}
+
+		found, err := Validate(ctx, obj)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, found...)
+	}
+
+	return res, nil
+}
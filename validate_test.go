@@ -0,0 +1,106 @@
+package crdgen_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestViolations(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   []string
+	}{
+		{
+			name:   "valid",
+			schema: `{"type": "object", "properties": {"replicas": {"type": "integer", "default": 1}}}`,
+		},
+		{
+			name: "defaults",
+			schema: `{
+				"type": "object",
+				"properties": {
+					"replicas": {"type": "integer", "default": "three"},
+					"size": {"type": "integer", "default": 3, "minimum": 5},
+					"mode": {"type": "string", "enum": ["a", "b"], "default": "c"}
+				}
+			}`,
+			want: []string{
+				"spec.versions[0].schema.openAPIV3Schema.properties[spec].properties[replicas].default",
+				"spec.versions[0].schema.openAPIV3Schema.properties[spec].properties[size].default",
+				"spec.versions[0].schema.openAPIV3Schema.properties[spec].properties[mode].default",
+			},
+		},
+		{
+			name:   "pattern",
+			schema: `{"type": "object", "properties": {"name": {"type": "string", "pattern": "[a-z"}}}`,
+			want: []string{
+				"spec.versions[0].schema.openAPIV3Schema.properties[spec].properties[name].pattern",
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			res := crdgen.Generate(context.TODO(), crdgen.Options{
+				GVK: schema.GroupVersionKind{
+					Group:   "example.org",
+					Version: "v1alpha1",
+					Kind:    "Xapp",
+				},
+				Native:               true,
+				SpecJsonSchemaGetter: getter.Bytes(tc.schema),
+			})
+			if res.Err != nil {
+				t.Fatal(res.Err)
+			}
+
+			if len(res.Violations) != len(tc.want) {
+				t.Fatalf("expected %d violations, got: %v", len(tc.want), res.Violations)
+			}
+
+			for _, want := range tc.want {
+				found := false
+				for _, el := range res.Violations {
+					found = found || (el.CRD == "xapps.example.org" && el.Field == want)
+				}
+				if !found {
+					t.Errorf("expected a violation of '%s', got: %v", want, res.Violations)
+				}
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	res := crdgen.Generate(context.TODO(), crdgen.Options{
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Xapp",
+		},
+		Native:               true,
+		Managed:              true,
+		SpecJsonSchemaGetter: getter.File("./testdata/issue.43.hack.json"),
+	})
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+
+	obj := res.CRDs["xapps.example.org"]
+	obj.Name = "xapp.example.org"
+
+	got, err := crdgen.Validate(context.TODO(), obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 1 || got[0].Field != "metadata.name" || !strings.Contains(got[0].Detail, "spec.names.plural") {
+		t.Errorf("expected a violation of the CRD name, got: %v", got)
+	}
+}
@@ -0,0 +1,410 @@
+// Package validator validates custom resources against the CRDs
+// generated by crdgen without a cluster, as the API server would on
+// create: the unknown fields are pruned, the defaults applied and the
+// objects checked with the OpenAPI schema and the CEL rules.
+package validator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/internal/ptr"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	structuralschema "k8s.io/apiextensions-apiserver/pkg/apiserver/schema"
+	"k8s.io/apiextensions-apiserver/pkg/apiserver/schema/cel"
+	structuraldefaulting "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/defaulting"
+	structurallisttype "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/listtype"
+	schemaobjectmeta "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/objectmeta"
+	structuralpruning "k8s.io/apiextensions-apiserver/pkg/apiserver/schema/pruning"
+	apiservervalidation "k8s.io/apiextensions-apiserver/pkg/apiserver/validation"
+	"k8s.io/apimachinery/pkg/api/validation"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	utiljson "k8s.io/apimachinery/pkg/util/json"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	celconfig "k8s.io/apiserver/pkg/apis/cel"
+)
+
+// Violation is a rule of the schema broken by a custom resource.
+type Violation struct {
+	// Field is the JSON path of the offending field, e.g. 'spec.replicas'.
+	Field string
+	// Type is the kind of violation, e.g. 'Invalid value' or 'Required value'.
+	Type   string
+	Detail string
+}
+
+func (v Violation) String() string {
+	res := v.Type
+	if len(v.Field) > 0 {
+		res = v.Field + ": " + res
+	}
+	if len(v.Detail) > 0 {
+		res += ": " + v.Detail
+	}
+	return res
+}
+
+// Report is the outcome of the validation of a custom resource.
+type Report struct {
+	// Index of the object in the validated documents, starting from 0.
+	Index int
+	// GVK of the object, as declared by its apiVersion and kind.
+	GVK       schema.GroupVersionKind
+	Namespace string
+	Name      string
+	// Object is the custom resource as the API server would store
+	// it: without the unknown fields and with the defaults applied.
+	Object map[string]any
+	// Pruned are the JSON paths of the unknown fields dropped,
+	// e.g. 'spec.replica'.
+	Pruned     []string
+	Violations []Violation
+}
+
+// Valid reports whether the API server would accept the object.
+func (r Report) Valid() bool {
+	return len(r.Violations) == 0
+}
+
+func (r Report) String() string {
+	name := r.Name
+	if len(r.Namespace) > 0 {
+		name = r.Namespace + "/" + name
+	}
+	return fmt.Sprintf("%s %s", r.GVK.Kind, name)
+}
+
+// Validator validates the custom resources of a set of CRDs.
+type Validator struct {
+	versions map[schema.GroupVersionKind]*version
+}
+
+// version holds the schemas of a version of a CRD.
+type version struct {
+	served     bool
+	namespaced bool
+	preserve   bool
+	structural *structuralschema.Structural
+	schema     apiservervalidation.SchemaValidator
+	cel        *cel.Validator
+}
+
+// New returns a validator of the custom resources of the CRDs.
+func New(crds ...*apiextensionsv1.CustomResourceDefinition) (*Validator, error) {
+	res := &Validator{
+		versions: map[schema.GroupVersionKind]*version{},
+	}
+
+	for _, obj := range crds {
+		in := obj.DeepCopy()
+		// the API server defaults the CRD before serving it
+		apiextensionsv1.SetObjectDefaults_CustomResourceDefinition(in)
+
+		for _, ver := range in.Spec.Versions {
+			gvk := schema.GroupVersionKind{
+				Group:   in.Spec.Group,
+				Version: ver.Name,
+				Kind:    in.Spec.Names.Kind,
+			}
+			if _, ok := res.versions[gvk]; ok {
+				return nil, fmt.Errorf("duplicate version '%s' of kind '%s'", ver.Name, gvk.GroupKind())
+			}
+
+			el, err := newVersion(in, ver)
+			if err != nil {
+				return nil, fmt.Errorf("CRD '%s', version '%s': %w", in.Name, ver.Name, err)
+			}
+			res.versions[gvk] = el
+		}
+	}
+
+	return res, nil
+}
+
+// ForResult returns a validator of the custom resources of the CRDs
+// generated by crdgen.
+func ForResult(res crdgen.Result) (*Validator, error) {
+	if res.Err != nil {
+		return nil, res.Err
+	}
+	if len(res.CRDs) == 0 {
+		return nil, errors.New("no CRD generated")
+	}
+
+	names := make([]string, 0, len(res.CRDs))
+	for name := range res.CRDs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	all := make([]*apiextensionsv1.CustomResourceDefinition, 0, len(names))
+	for _, name := range names {
+		all = append(all, res.CRDs[name])
+	}
+
+	return New(all...)
+}
+
+func newVersion(crd *apiextensionsv1.CustomResourceDefinition, ver apiextensionsv1.CustomResourceDefinitionVersion) (*version, error) {
+	res := &version{
+		served:     ver.Served,
+		namespaced: crd.Spec.Scope == apiextensionsv1.NamespaceScoped,
+	}
+
+	in := &apiextensionsv1.JSONSchemaProps{
+		Type:                   "object",
+		XPreserveUnknownFields: ptr.To(true),
+	}
+	if ver.Schema != nil && ver.Schema.OpenAPIV3Schema != nil {
+		in = ver.Schema.OpenAPIV3Schema
+	}
+
+	out := &apiextensions.JSONSchemaProps{}
+	err := apiextensionsv1.Convert_v1_JSONSchemaProps_To_apiextensions_JSONSchemaProps(in, out, nil)
+	if err != nil {
+		return nil, fmt.Errorf("converting the schema: %w", err)
+	}
+
+	res.structural, err = structuralschema.NewStructural(out)
+	if err != nil {
+		return nil, fmt.Errorf("the schema is not structural: %w", err)
+	}
+	res.preserve = res.structural.XPreserveUnknownFields
+
+	res.schema, _, err = apiservervalidation.NewSchemaValidator(out)
+	if err != nil {
+		return nil, err
+	}
+
+	res.cel = cel.NewValidator(res.structural, true, celconfig.PerCallLimit)
+
+	return res, nil
+}
+
+// Validate validates the custom resources in the YAML documents, or in
+// the JSON objects, and returns a report for each of them.
+func (v *Validator) Validate(ctx context.Context, dat []byte) ([]Report, error) {
+	dec := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(dat), 4096)
+
+	res := []Report{}
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("decoding object %d: %w", len(res), err)
+		}
+
+		raw = bytes.TrimSpace(raw)
+		if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+			continue
+		}
+
+		obj := map[string]any{}
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, fmt.Errorf("decoding object %d: %w", len(res), err)
+		}
+
+		rep := v.Object(ctx, obj)
+		rep.Index = len(res)
+		res = append(res, rep)
+	}
+
+	return res, nil
+}
+
+// Object validates the custom resource, which is left untouched.
+func (v *Validator) Object(ctx context.Context, obj map[string]any) Report {
+	obj, err := normalize(obj)
+	if err != nil {
+		return Report{
+			Violations: violations(field.ErrorList{field.Invalid(nil, nil, err.Error())}),
+		}
+	}
+
+	apiVersion, _ := obj["apiVersion"].(string)
+	kind, _ := obj["kind"].(string)
+
+	res := Report{Object: obj}
+	if gv, err := schema.ParseGroupVersion(apiVersion); err == nil {
+		res.GVK = gv.WithKind(kind)
+	}
+
+	ver, errs := v.lookup(apiVersion, kind)
+	if len(errs) > 0 {
+		res.Violations = violations(errs)
+		return res
+	}
+
+	// decoding: the unknown fields are pruned and the defaults applied
+	meta, found, pruned, err := schemaobjectmeta.GetObjectMetaWithOptions(obj, schemaobjectmeta.ObjectMetaOptions{
+		DropMalformedFields:     true,
+		ReturnUnknownFieldPaths: true,
+	})
+	if err != nil {
+		res.Violations = violations(field.ErrorList{
+			field.Invalid(field.NewPath("metadata"), nil, err.Error()),
+		})
+		return res
+	}
+	res.Pruned = append(res.Pruned, pruned...)
+	if !found {
+		// validated as empty: the missing name is reported
+		meta = &metav1.ObjectMeta{}
+	}
+
+	if !ver.preserve {
+		res.Pruned = append(res.Pruned, structuralpruning.PruneWithOptions(obj, ver.structural, true, structuralschema.UnknownFieldPathOptions{
+			TrackUnknownFieldPaths: true,
+		})...)
+		structuraldefaulting.PruneNonNullableNullsWithoutDefaults(obj, ver.structural)
+	}
+
+	ferr, pruned := schemaobjectmeta.CoerceWithOptions(nil, obj, ver.structural, false, schemaobjectmeta.CoerceOptions{
+		DropInvalidFields:       true,
+		ReturnUnknownFieldPaths: true,
+	})
+	if ferr != nil {
+		res.Violations = violations(field.ErrorList{ferr})
+		return res
+	}
+	res.Pruned = append(res.Pruned, pruned...)
+
+	// the pruning drops the implicit fields, which are restored
+	obj["apiVersion"] = apiVersion
+	obj["kind"] = kind
+	if found {
+		if err := schemaobjectmeta.SetObjectMeta(obj, meta); err != nil {
+			res.Violations = violations(field.ErrorList{
+				field.Invalid(field.NewPath("metadata"), nil, err.Error()),
+			})
+			return res
+		}
+	}
+
+	structuraldefaulting.Default(obj, ver.structural)
+
+	res.Namespace = meta.Namespace
+	res.Name = meta.Name
+
+	// validation on create
+	errs = append(errs, validation.ValidateObjectMeta(createMeta(meta, ver.namespaced), ver.namespaced, validation.NameIsDNSSubdomain, field.NewPath("metadata"))...)
+	errs = append(errs, apiservervalidation.ValidateCustomResource(nil, obj, ver.schema)...)
+	errs = append(errs, schemaobjectmeta.Validate(nil, obj, ver.structural, false)...)
+	errs = append(errs, structurallisttype.ValidateListSetsAndMaps(nil, ver.structural, obj)...)
+
+	if ver.cel != nil {
+		if blocking(errs) {
+			errs = append(errs, field.Invalid(nil, nil, "some validation rules were not checked because the object was invalid; correct the existing errors to complete validation"))
+		} else {
+			found, _ := ver.cel.Validate(ctx, nil, ver.structural, obj, nil, celconfig.RuntimeCELCostBudget)
+			errs = append(errs, found...)
+		}
+	}
+
+	res.Violations = violations(errs)
+	return res
+}
+
+// lookup returns the schemas of the version of the kind.
+func (v *Validator) lookup(apiVersion, kind string) (*version, field.ErrorList) {
+	gv, err := schema.ParseGroupVersion(apiVersion)
+	if err != nil || len(apiVersion) == 0 {
+		return nil, field.ErrorList{field.Invalid(field.NewPath("apiVersion"), apiVersion, "must be a valid group/version")}
+	}
+	if len(kind) == 0 {
+		return nil, field.ErrorList{field.Required(field.NewPath("kind"), "")}
+	}
+
+	ver, ok := v.versions[gv.WithKind(kind)]
+	if !ok {
+		var known []string
+		for gvk := range v.versions {
+			if gvk.Kind == kind && gvk.Group == gv.Group {
+				known = append(known, gvk.GroupVersion().String())
+			}
+		}
+		if len(known) > 0 {
+			return nil, field.ErrorList{field.NotSupported(field.NewPath("apiVersion"), apiVersion, known)}
+		}
+		return nil, field.ErrorList{field.Invalid(field.NewPath("kind"), kind, fmt.Sprintf("no CRD of kind '%s' in group '%s'", kind, gv.Group))}
+	}
+
+	if !ver.served {
+		return nil, field.ErrorList{field.Invalid(field.NewPath("apiVersion"), apiVersion, "version is not served")}
+	}
+
+	return ver, nil
+}
+
+// blocking reports whether the errors prevent the evaluation of
+// the CEL rules, as in the API server.
+func blocking(errs field.ErrorList) bool {
+	for _, el := range errs {
+		switch el.Type {
+		case field.ErrorTypeNotSupported, field.ErrorTypeRequired, field.ErrorTypeTooLong,
+			field.ErrorTypeTooMany, field.ErrorTypeTypeInvalid:
+			return true
+		}
+	}
+	return false
+}
+
+// violations returns the errors sorted by field, the schema
+// validation reporting them in no particular order.
+func violations(errs field.ErrorList) []Violation {
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].Field < errs[j].Field
+	})
+
+	res := make([]Violation, 0, len(errs))
+	for _, el := range errs {
+		res = append(res, Violation{
+			Field:  el.Field,
+			Type:   el.Type.String(),
+			Detail: strings.TrimPrefix(strings.TrimPrefix(el.ErrorBody(), el.Type.String()), ": "),
+		})
+	}
+	return res
+}
+
+// normalize returns a copy of the object holding the JSON values
+// decoded by the API server, the numbers as int64 when possible.
+func normalize(obj map[string]any) (map[string]any, error) {
+	dat, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	res := map[string]any{}
+	if err := utiljson.Unmarshal(dat, &res); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// createMeta returns the metadata as completed by the API server
+// on create: the namespace of the request and the generated name.
+func createMeta(meta *metav1.ObjectMeta, namespaced bool) *metav1.ObjectMeta {
+	res := meta.DeepCopy()
+	if namespaced && len(res.Namespace) == 0 {
+		res.Namespace = metav1.NamespaceDefault
+	}
+	if len(res.Name) == 0 && len(res.GenerateName) > 0 {
+		// stands for the random suffix of the API server
+		res.Name = res.GenerateName + "x0000"
+	}
+	return res
+}
@@ -0,0 +1,307 @@
+package validator_test
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen"
+	"github.com/krateoplatformops/crdgen/getter"
+	"github.com/krateoplatformops/crdgen/internal/ptr"
+	"github.com/krateoplatformops/crdgen/validator"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const spec = `{
+	"type": "object",
+	"required": ["name", "replicas"],
+	"properties": {
+		"name": {"type": "string", "pattern": "^[a-z][a-z0-9-]+$"},
+		"replicas": {"type": "integer", "minimum": 1, "maximum": 5},
+		"mode": {"type": "string", "enum": ["safe", "fast"]},
+		"zone": {"type": "string", "default": "eu-west-1"}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	res := crdgen.Generate(context.TODO(), crdgen.Options{
+		GVK: schema.GroupVersionKind{
+			Group:   "example.org",
+			Version: "v1alpha1",
+			Kind:    "Xapp",
+		},
+		Native:               true,
+		SpecJsonSchemaGetter: getter.Bytes(spec),
+	})
+
+	v, err := validator.ForResult(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		object string
+		want   []string
+		pruned []string
+	}{
+		{
+			name: "valid",
+			object: `
+apiVersion: example.org/v1alpha1
+kind: Xapp
+metadata:
+  name: demo
+spec:
+  name: demo
+  replicas: 3
+  mode: fast
+`,
+		},
+		{
+			name: "required",
+			object: `
+apiVersion: example.org/v1alpha1
+kind: Xapp
+metadata:
+  name: demo
+spec:
+  replicas: 3
+`,
+			want: []string{"spec.name: Required value"},
+		},
+		{
+			name: "enum",
+			object: `
+apiVersion: example.org/v1alpha1
+kind: Xapp
+metadata:
+  name: demo
+spec:
+  name: demo
+  replicas: 3
+  mode: slow
+`,
+			want: []string{`spec.mode: Unsupported value: "slow": supported values: "safe", "fast"`},
+		},
+		{
+			name: "pattern and bounds",
+			object: `
+apiVersion: example.org/v1alpha1
+kind: Xapp
+metadata:
+  name: demo
+spec:
+  name: Demo
+  replicas: 9
+`,
+			want: []string{
+				`spec.name: Invalid value: "Demo": spec.name in body should match '^[a-z][a-z0-9-]+$'`,
+				"spec.replicas: Invalid value: 9: spec.replicas in body should be less than or equal to 5",
+			},
+		},
+		{
+			name: "pruned",
+			object: `
+apiVersion: example.org/v1alpha1
+kind: Xapp
+metadata:
+  name: demo
+spec:
+  name: demo
+  replicas: 1
+  replica: 2
+`,
+			pruned: []string{"spec.replica"},
+		},
+		{
+			name: "metadata",
+			object: `
+apiVersion: example.org/v1alpha1
+kind: Xapp
+metadata:
+  name: Demo
+spec:
+  name: demo
+  replicas: 1
+`,
+			want: []string{`metadata.name: Invalid value: "Demo": a lowercase RFC 1123 subdomain must consist of lower case alphanumeric characters, '-' or '.', and must start and end with an alphanumeric character (e.g. 'example.com', regex used for validation is '[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*')`},
+		},
+		{
+			name: "no metadata",
+			object: `
+apiVersion: example.org/v1alpha1
+kind: Xapp
+spec:
+  name: demo
+  replicas: 1
+`,
+			want: []string{"metadata.name: Required value: name or generateName is required"},
+		},
+		{
+			name: "unknown version",
+			object: `
+apiVersion: example.org/v1
+kind: Xapp
+metadata:
+  name: demo
+`,
+			want: []string{`apiVersion: Unsupported value: "example.org/v1": supported values: "example.org/v1alpha1"`},
+		},
+		{
+			name: "unknown kind",
+			object: `
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: demo
+`,
+			want: []string{`kind: Invalid value: "ConfigMap": no CRD of kind 'ConfigMap' in group ''`},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			all, err := v.Validate(context.TODO(), []byte(tc.object))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(all) != 1 {
+				t.Fatalf("expected 1 report, got %d", len(all))
+			}
+
+			got := []string{}
+			for _, el := range all[0].Violations {
+				got = append(got, el.String())
+			}
+			if len(tc.want) == 0 {
+				tc.want = []string{}
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("expected violations:\n%s\ngot:\n%s", strings.Join(tc.want, "\n"), strings.Join(got, "\n"))
+			}
+			if all[0].Valid() != (len(tc.want) == 0) {
+				t.Errorf("expected valid %t", len(tc.want) == 0)
+			}
+
+			if !reflect.DeepEqual(all[0].Pruned, tc.pruned) {
+				t.Errorf("expected pruned fields %v, got %v", tc.pruned, all[0].Pruned)
+			}
+		})
+	}
+}
+
+func TestObject(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "xapps.example.org"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.org",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Kind: "Xapp", ListKind: "XappList", Plural: "xapps", Singular: "xapp",
+			},
+			Scope: apiextensionsv1.NamespaceScoped,
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:    "v1alpha1",
+				Served:  true,
+				Storage: true,
+				Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							"spec": {
+								Type: "object",
+								Properties: map[string]apiextensionsv1.JSONSchemaProps{
+									"min":  {Type: "integer"},
+									"max":  {Type: "integer"},
+									"zone": {Type: "string", Default: &apiextensionsv1.JSON{Raw: []byte(`"eu-west-1"`)}},
+								},
+								XValidations: apiextensionsv1.ValidationRules{{
+									Rule:    "self.min <= self.max",
+									Message: "min must not exceed max",
+								}},
+							},
+						},
+					},
+				},
+			}},
+		},
+	}
+
+	v, err := validator.New(crd)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := map[string]any{
+		"apiVersion": "example.org/v1alpha1",
+		"kind":       "Xapp",
+		"metadata":   map[string]any{"name": "demo", "namespace": "demo"},
+		"spec":       map[string]any{"min": 1, "max": 3},
+	}
+
+	res := v.Object(context.TODO(), obj)
+	if !res.Valid() {
+		t.Fatalf("unexpected violations: %v", res.Violations)
+	}
+	if got := res.String(); got != "Xapp demo/demo" {
+		t.Errorf("expected 'Xapp demo/demo', got '%s'", got)
+	}
+
+	spec := res.Object["spec"].(map[string]any)
+	if spec["zone"] != "eu-west-1" {
+		t.Errorf("expected the default zone, got %v", spec["zone"])
+	}
+	if _, ok := obj["spec"].(map[string]any)["zone"]; ok {
+		t.Errorf("expected the object left untouched")
+	}
+
+	obj["spec"] = map[string]any{"min": 5, "max": 3}
+	res = v.Object(context.TODO(), obj)
+
+	want := []validator.Violation{{
+		Field:  "spec",
+		Type:   "Invalid value",
+		Detail: `"object": min must not exceed max`,
+	}}
+	if !reflect.DeepEqual(res.Violations, want) {
+		t.Errorf("expected violations %v, got %v", want, res.Violations)
+	}
+}
+
+func TestNew(t *testing.T) {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "xapps.example.org"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "example.org",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{Kind: "Xapp", Plural: "xapps"},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{{
+				Name:   "v1alpha1",
+				Served: true,
+				Schema: &apiextensionsv1.CustomResourceValidation{
+					OpenAPIV3Schema: &apiextensionsv1.JSONSchemaProps{
+						Type: "object",
+						Properties: map[string]apiextensionsv1.JSONSchemaProps{
+							// not structural: the type is missing
+							"spec": {Nullable: true, XPreserveUnknownFields: ptr.To(false)},
+						},
+					},
+				},
+			}},
+		},
+	}
+
+	if _, err := validator.New(crd); err == nil {
+		t.Errorf("expected an error for a non structural schema")
+	}
+
+	crd.Spec.Versions[0].Schema = nil
+	if _, err := validator.New(crd); err != nil {
+		t.Errorf("unexpected error for a CRD without schema: %v", err)
+	}
+	if _, err := validator.New(crd, crd); err == nil {
+		t.Errorf("expected an error for the duplicate CRD")
+	}
+}
@@ -0,0 +1,150 @@
+package crdgen
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/krateoplatformops/crdgen/internal/coder"
+	"github.com/krateoplatformops/crdgen/internal/ptr"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Version describes one of the versions served by the generated CRD.
+type Version struct {
+	// Name of the version, e.g. "v1alpha1".
+	Name string
+	// Served reports whether the version is served by the API server;
+	// nil means served.
+	Served *bool
+	// Storage marks the version used to persist the resources,
+	// exactly one version must be marked as storage.
+	Storage bool
+	// Deprecated marks the version as deprecated; when DeprecationWarning
+	// is not empty it overrides the warning returned to the API clients.
+	Deprecated         bool
+	DeprecationWarning string
+
+	SpecJsonSchemaGetter   JsonSchemaGetter
+	StatusJsonSchemaGetter JsonSchemaGetter
+}
+
+// versions returns the versions requested for the kind; when Versions
+// is empty the single version described by GVK and the schema getters.
+func (k *Kind) versions() []Version {
+	if len(k.Versions) > 0 {
+		return k.Versions
+	}
+
+	return []Version{{
+		Name:                   k.GVK.Version,
+		Storage:                true,
+		SpecJsonSchemaGetter:   k.SpecJsonSchemaGetter,
+		StatusJsonSchemaGetter: k.StatusJsonSchemaGetter,
+	}}
+}
+
+// resources validates the kind and fetches the JSON schemas of
+// every version returning the plan of its CRD.
+func resources(ctx context.Context, k Kind, timeout time.Duration) (*crdPlan, error) {
+	scope := k.Scope
+	switch scope {
+	case "":
+		scope = apiextensionsv1.NamespaceScoped
+	case apiextensionsv1.NamespaceScoped, apiextensionsv1.ClusterScoped:
+	default:
+		return nil, fmt.Errorf("invalid scope '%s' (expected: %s or %s)",
+			k.Scope, apiextensionsv1.NamespaceScoped, apiextensionsv1.ClusterScoped)
+	}
+
+	nms, err := resolveNames(k)
+	if err != nil {
+		return nil, err
+	}
+
+	vers := k.versions()
+
+	var storage string
+	seen := make(map[string]bool, len(vers))
+	for _, v := range vers {
+		if errs := validation.IsDNS1035Label(v.Name); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid version name '%s': %v", v.Name, errs)
+		}
+
+		if seen[v.Name] {
+			return nil, fmt.Errorf("duplicate version name '%s'", v.Name)
+		}
+		seen[v.Name] = true
+
+		if v.Storage {
+			if storage != "" {
+				return nil, fmt.Errorf("versions '%s' and '%s' are both marked as storage", storage, v.Name)
+			}
+			storage = v.Name
+		}
+
+		if v.SpecJsonSchemaGetter == nil {
+			return nil, fmt.Errorf("missing spec JSON schema getter for version '%s'", v.Name)
+		}
+	}
+
+	if storage == "" {
+		return nil, fmt.Errorf("exactly one version must be marked as storage")
+	}
+
+	all := make([]*coder.Resource, 0, len(vers))
+	for _, v := range vers {
+		nfo := &coder.Resource{
+			Group:              k.GVK.Group,
+			Version:            v.Name,
+			Kind:               k.GVK.Kind,
+			Scope:              string(scope),
+			Plural:             nms.plural,
+			Singular:           nms.singular,
+			ShortNames:         nms.shortNames,
+			ListKind:           nms.listKind,
+			Categories:         k.Categories,
+			Managed:            k.Managed,
+			Served:             ptr.Deref(v.Served, true),
+			Storage:            v.Storage,
+			Deprecated:         v.Deprecated,
+			DeprecationWarning: v.DeprecationWarning,
+		}
+
+		ref := SchemaRef{Kind: k.GVK.Kind, Version: v.Name, Schema: "spec"}
+		nfo.SpecSchema, err = v.SpecJsonSchemaGetter.Get(ctx)
+		if err != nil {
+			return nil, fetchError(ctx, ref, timeout, err)
+		}
+
+		if v.StatusJsonSchemaGetter != nil {
+			ref.Schema = "status"
+			nfo.StatusSchema, err = v.StatusJsonSchemaGetter.Get(ctx)
+			if err != nil {
+				return nil, fetchError(ctx, ref, timeout, err)
+			}
+		}
+
+		all = append(all, nfo)
+	}
+
+	return &crdPlan{
+		kind:    k,
+		name:    fmt.Sprintf("%s.%s", nms.plural, k.GVK.Group),
+		storage: storage,
+		all:     all,
+	}, nil
+}
+
+// fetchError reports a timeout of the fetch stage, the getter error otherwise.
+func fetchError(ctx context.Context, ref SchemaRef, timeout time.Duration, err error) error {
+	if ctx.Err() != nil {
+		err = ctx.Err()
+		if timeout > 0 && errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s: %w", timeout, err)
+		}
+	}
+	return &FetchError{SchemaRef: ref, Err: err}
+}
@@ -0,0 +1,72 @@
+package crdgen
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/krateoplatformops/crdgen/internal/coder"
+)
+
+func TestDefaultCodeGeneratorOptions(t *testing.T) {
+	const total = 16
+
+	cfgs := make([]coder.Options, total)
+	logs := make([]bytes.Buffer, total)
+	errs := make([]error, total)
+
+	var wg sync.WaitGroup
+	for i := 0; i < total; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			cfg, err := defaultCodeGeneratorOptions("xapp")
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			cfg.Logger = log.New(&logs[i], "", 0)
+			cfgs[i] = cfg
+
+			errs[i] = coder.Do([]*coder.Resource{{
+				Group:      "example.org",
+				Version:    "v1alpha1",
+				Kind:       fmt.Sprintf("Xapp%d", i%4),
+				SpecSchema: []byte(`{"type": "object", "properties": {"replicas": {"type": "integer"}}}`),
+				Served:     true,
+				Storage:    true,
+			}}, cfg)
+		}(i)
+	}
+	wg.Wait()
+
+	seen := map[string]bool{}
+	for i, cfg := range cfgs {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+		defer os.RemoveAll(cfg.Workdir)
+
+		if seen[cfg.Workdir] {
+			t.Errorf("workdir '%s' shared by concurrent generations", cfg.Workdir)
+		}
+		seen[cfg.Workdir] = true
+
+		// the logger of each generation only gets its own messages
+		out := logs[i].String()
+		if !strings.Contains(out, cfg.Workdir) {
+			t.Errorf("generation #%d: expected its workdir in the log, got:\n%s", i, out)
+		}
+		if n := strings.Count(out, "Generating code:"); n != 1 {
+			t.Errorf("generation #%d: expected 1 generation logged, got %d", i, n)
+		}
+	}
+
+	if _, err := defaultCodeGeneratorOptions("../xapp"); err == nil {
+		t.Errorf("expected an error for a workdir containing path separators")
+	}
+}